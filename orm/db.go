@@ -3,15 +3,38 @@ package orm
 import (
     "database/sql"
     sqldriver "database/sql/driver"
-    _ "github.com/go-sql-driver/mysql"
+    "github.com/go-sql-driver/mysql"
+    "sync"
+    "time"
 )
 
+var dbLocations sync.Map //*sql.DB => *time.Location
+
 func OpenMysql(dataSourceName string) (*sql.DB, error) {
-    return sql.Open("mysql", dataSourceName)
+    return Open("mysql", dataSourceName)
 }
 
 func Open(driverName, dataSourceName string) (*sql.DB, error) {
-    return sql.Open(driverName, dataSourceName)
+    db, err := sql.Open(driverName, dataSourceName)
+    if err == nil && driverName == "mysql" {
+        if cfg, err := mysql.ParseDSN(dataSourceName); err == nil && cfg.Loc != nil {
+            SetDBLocation(db, cfg.Loc)
+        }
+    }
+    return db, err
+}
+
+//location of time values converted by driver, loc of dsn if opened by Open, OpenMysql
+//set it for db opened by OpenDB or sql.Open, UTC by default as the driver
+func SetDBLocation(db *sql.DB, loc *time.Location) {
+    dbLocations.Store(db, loc)
+}
+
+func dbLocation(db *sql.DB) *time.Location {
+    if v, ok := dbLocations.Load(db); ok {
+        return v.(*time.Location)
+    }
+    return time.UTC
 }
 
 func OpenDB(driver sqldriver.Connector) *sql.DB {
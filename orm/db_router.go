@@ -0,0 +1,228 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "math/rand"
+    "sync"
+    "time"
+)
+
+//pick read db for query, dbs[0] is write db, rest are read dbs
+type ReadPolicy interface {
+    ReadDB(dbs []*sql.DB) *sql.DB
+}
+
+//table with its own read policy
+type ReadPolicyTable interface {
+    ReadPolicy() ReadPolicy
+}
+
+var defaultReadPolicy ReadPolicy = RandomReadPolicy{}
+
+//read policy of all tables, except ReadPolicyTable
+func SetReadPolicy(p ReadPolicy) {
+    defaultReadPolicy = p
+}
+
+//rand get read db
+type RandomReadPolicy struct{}
+
+func (RandomReadPolicy) ReadDB(dbs []*sql.DB) *sql.DB {
+    if len(dbs) > 1 {
+        return dbs[rand.Intn(len(dbs)-1)+1]
+    } else if len(dbs) == 1 {
+        return dbs[0]
+    }
+    return nil
+}
+
+//weighted read dbs, checked by ping periodically
+//failed read db is removed after failThreshold pings, re-admitted after one success ping
+//write db is used if no read db healthy
+type HealthReadPolicy struct {
+    mu            sync.RWMutex
+    weights       map[*sql.DB]int
+    replicas      map[*sql.DB]*replicaState
+    interval      time.Duration
+    timeout       time.Duration
+    failThreshold int
+    startOnce     sync.Once
+    stopOnce      sync.Once
+    stop          chan struct{}
+}
+
+type replicaState struct {
+    healthy  bool
+    failures int
+}
+
+func NewHealthReadPolicy(interval time.Duration) *HealthReadPolicy {
+    if interval <= 0 {
+        interval = time.Second * 5
+    }
+    return &HealthReadPolicy{
+        weights:       make(map[*sql.DB]int),
+        replicas:      make(map[*sql.DB]*replicaState),
+        interval:      interval,
+        timeout:       time.Second,
+        failThreshold: 1,
+        stop:          make(chan struct{}),
+    }
+}
+
+//weight of read db, default 1, 0 to disable
+func (p *HealthReadPolicy) SetWeight(db *sql.DB, weight int) *HealthReadPolicy {
+    p.mu.Lock()
+    p.weights[db] = weight
+    p.mu.Unlock()
+    return p
+}
+
+//ping timeout, default 1s
+func (p *HealthReadPolicy) SetTimeout(timeout time.Duration) *HealthReadPolicy {
+    p.mu.Lock()
+    p.timeout = timeout
+    p.mu.Unlock()
+    return p
+}
+
+//failed pings before removed, default 1
+func (p *HealthReadPolicy) SetFailThreshold(n int) *HealthReadPolicy {
+    p.mu.Lock()
+    p.failThreshold = n
+    p.mu.Unlock()
+    return p
+}
+
+func (p *HealthReadPolicy) ReadDB(dbs []*sql.DB) *sql.DB {
+    if len(dbs) == 0 {
+        return nil
+    }
+    p.startOnce.Do(func() {
+        go p.run()
+    })
+
+    var candidates []*sql.DB
+    var weights []int
+    total := 0
+
+    p.mu.RLock()
+    var unknown []*sql.DB
+    for _, v := range dbs[1:] {
+        state, ok := p.replicas[v]
+        if ok == false {
+            unknown = append(unknown, v)
+        } else if state.healthy == false {
+            continue
+        }
+        weight, ok := p.weights[v]
+        if ok == false {
+            weight = 1
+        }
+        if weight > 0 {
+            candidates = append(candidates, v)
+            weights = append(weights, weight)
+            total += weight
+        }
+    }
+    p.mu.RUnlock()
+
+    if len(unknown) > 0 {
+        p.mu.Lock()
+        for _, v := range unknown {
+            if _, ok := p.replicas[v]; ok == false {
+                p.replicas[v] = &replicaState{healthy: true}
+            }
+        }
+        p.mu.Unlock()
+    }
+
+    if total == 0 {
+        return dbs[0]
+    }
+    n := rand.Intn(total)
+    for k, v := range weights {
+        if n < v {
+            return candidates[k]
+        }
+        n -= v
+    }
+    return dbs[0]
+}
+
+//health of read db, true if not checked yet
+func (p *HealthReadPolicy) Healthy(db *sql.DB) bool {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+    state, ok := p.replicas[db]
+    return ok == false || state.healthy
+}
+
+//remove read db until next success ping, like after a connection error
+func (p *HealthReadPolicy) MarkDown(db *sql.DB) {
+    p.mu.Lock()
+    state, ok := p.replicas[db]
+    if ok == false {
+        state = &replicaState{}
+        p.replicas[db] = state
+    }
+    state.healthy = false
+    state.failures = p.failThreshold
+    p.mu.Unlock()
+}
+
+//stop health checks
+func (p *HealthReadPolicy) Stop() {
+    p.stopOnce.Do(func() {
+        close(p.stop)
+    })
+}
+
+func (p *HealthReadPolicy) run() {
+    ticker := time.NewTicker(p.interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-p.stop:
+            return
+        case <-ticker.C:
+            p.check()
+        }
+    }
+}
+
+func (p *HealthReadPolicy) check() {
+    p.mu.RLock()
+    dbs := make([]*sql.DB, 0, len(p.replicas))
+    for k := range p.replicas {
+        dbs = append(dbs, k)
+    }
+    timeout := p.timeout
+    p.mu.RUnlock()
+
+    for _, db := range dbs {
+        ctx, cancel := context.WithTimeout(context.Background(), timeout)
+        err := db.PingContext(ctx)
+        cancel()
+
+        p.mu.Lock()
+        state := p.replicas[db]
+        if err != nil {
+            state.failures++
+            if state.failures >= p.failThreshold {
+                if state.healthy && errorLogger != nil {
+                    errorLogger.Error("read db removed", err)
+                }
+                state.healthy = false
+            }
+        } else {
+            if state.healthy == false && infoLogger != nil {
+                infoLogger.Info("read db re-admitted")
+            }
+            state.failures = 0
+            state.healthy = true
+        }
+        p.mu.Unlock()
+    }
+}
@@ -0,0 +1,138 @@
+package orm
+
+import (
+    "database/sql"
+    "errors"
+    "testing"
+    "time"
+)
+
+//read dbs picked by policy in n reads
+func countReadDBs(p ReadPolicy, dbs []*sql.DB, n int) map[*sql.DB]int {
+    ret := make(map[*sql.DB]int)
+    for i := 0; i < n; i++ {
+        ret[p.ReadDB(dbs)]++
+    }
+    return ret
+}
+
+func TestHealthReadPolicyWeights(t *testing.T) {
+    primary, _ := newFakeDB()
+    replica1, _ := newFakeDB()
+    replica2, _ := newFakeDB()
+    dbs := []*sql.DB{primary, replica1, replica2}
+
+    p := NewHealthReadPolicy(time.Hour).SetWeight(replica1, 3)
+    defer p.Stop()
+
+    counts := countReadDBs(p, dbs, 4000)
+    if counts[primary] != 0 {
+        t.Errorf("want no read from primary, got %d", counts[primary])
+    }
+    if share := float64(counts[replica1]) / 4000; share < 0.7 || share > 0.8 {
+        t.Errorf("want 75%% reads from replica of weight 3, got %.2f", share)
+    }
+
+    p.SetWeight(replica2, 0)
+    if counts := countReadDBs(p, dbs, 100); counts[replica1] != 100 {
+        t.Errorf("want all reads from replica1 if weight of replica2 is 0, got %v", counts)
+    }
+}
+
+func TestHealthReadPolicyHealthCheck(t *testing.T) {
+    primary, _ := newFakeDB()
+    replica1, fake1 := newFakeDB()
+    replica2, fake2 := newFakeDB()
+    dbs := []*sql.DB{primary, replica1, replica2}
+
+    p := NewHealthReadPolicy(time.Hour)
+    defer p.Stop()
+    countReadDBs(p, dbs, 1)
+
+    t.Run("mark down", func(t *testing.T) {
+        p.MarkDown(replica1)
+        if p.Healthy(replica1) {
+            t.Error("want replica1 down")
+        }
+        if counts := countReadDBs(p, dbs, 100); counts[replica2] != 100 {
+            t.Errorf("want all reads from replica2, got %v", counts)
+        }
+    })
+
+    t.Run("re-admitted after ping", func(t *testing.T) {
+        p.check()
+        if p.Healthy(replica1) == false {
+            t.Error("want replica1 re-admitted")
+        }
+        if counts := countReadDBs(p, dbs, 1000); counts[replica1] == 0 || counts[replica2] == 0 {
+            t.Errorf("want reads from both replicas, got %v", counts)
+        }
+    })
+
+    t.Run("removed after failed pings", func(t *testing.T) {
+        p.SetFailThreshold(2)
+        fake2.setPingErr(errors.New("connection refused"))
+        p.check()
+        if p.Healthy(replica2) == false {
+            t.Error("want replica2 healthy before 2 failed pings")
+        }
+        p.check()
+        if p.Healthy(replica2) {
+            t.Error("want replica2 removed after 2 failed pings")
+        }
+        if counts := countReadDBs(p, dbs, 100); counts[replica1] != 100 {
+            t.Errorf("want all reads from replica1, got %v", counts)
+        }
+    })
+
+    t.Run("fallback to primary", func(t *testing.T) {
+        fake1.setPingErr(errors.New("connection refused"))
+        p.check()
+        p.check()
+        if counts := countReadDBs(p, dbs, 100); counts[primary] != 100 {
+            t.Errorf("want all reads from primary, got %v", counts)
+        }
+
+        fake1.setPingErr(nil)
+        p.check()
+        if got := p.ReadDB(dbs); got != replica1 {
+            t.Error("want replica1 after success ping")
+        }
+    })
+}
+
+type testReplicaRow struct {
+    Id int `json:"id"`
+}
+
+var testReplicaPolicy = NewHealthReadPolicy(time.Hour)
+
+func (*testReplicaRow) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testReplicaRow) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testReplicaRow) TableName() string {
+    return "test_replica_row"
+}
+
+func (*testReplicaRow) ReadPolicy() ReadPolicy {
+    return testReplicaPolicy
+}
+
+func TestReadPolicyTable(t *testing.T) {
+    primary, _ := newFakeDB()
+    replica1, _ := newFakeDB()
+    replica2, _ := newFakeDB()
+    testReplicaPolicy.SetWeight(replica1, 0)
+
+    if got := NewQuery(new(testReplicaRow), primary, replica1, replica2).readDB(); got != replica2 {
+        t.Error("want read db by policy of table")
+    }
+    if got := NewQuery(new(testReplicaRow), primary).readDB(); got != primary {
+        t.Error("want primary if no read db")
+    }
+}
@@ -0,0 +1,49 @@
+package orm
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+type readYourWritesKey struct{}
+
+type readYourWrites struct {
+    mu        sync.Mutex
+    window    time.Duration
+    lastWrite time.Time
+}
+
+//queries WithContext(ctx) read from write db after a write WithContext(ctx)
+//for window duration, or for the rest of ctx if window is 0
+func WithReadYourWrites(ctx context.Context, window time.Duration) context.Context {
+    return context.WithValue(ctx, readYourWritesKey{}, &readYourWrites{window: window})
+}
+
+func markWritten(ctx *context.Context) {
+    if ctx == nil {
+        return
+    }
+    session, ok := (*ctx).Value(readYourWritesKey{}).(*readYourWrites)
+    if ok {
+        session.mu.Lock()
+        session.lastWrite = time.Now()
+        session.mu.Unlock()
+    }
+}
+
+func mustReadWriteDB(ctx *context.Context) bool {
+    if ctx == nil {
+        return false
+    }
+    session, ok := (*ctx).Value(readYourWritesKey{}).(*readYourWrites)
+    if ok == false {
+        return false
+    }
+    session.mu.Lock()
+    defer session.mu.Unlock()
+    if session.lastWrite.IsZero() {
+        return false
+    }
+    return session.window <= 0 || time.Since(session.lastWrite) < session.window
+}
@@ -0,0 +1,73 @@
+package orm
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestReadYourWrites(t *testing.T) {
+    primary, primaryFake := newFakeDB()
+    replica, replicaFake := newFakeDB()
+    table := new(testRow)
+
+    //db of statements since last call
+    var primaryCount, replicaCount int
+    lastDB := func() string {
+        p, r := len(primaryFake.recorded())-primaryCount, len(replicaFake.recorded())-replicaCount
+        primaryCount, replicaCount = primaryCount+p, replicaCount+r
+        if p > 0 && r == 0 {
+            return "primary"
+        } else if r > 0 && p == 0 {
+            return "replica"
+        }
+        return "none"
+    }
+    get := func(ctx context.Context) string {
+        _, _ = NewQuery(table, primary, replica).WithContext(ctx).Get(1)
+        return lastDB()
+    }
+    update := func(ctx context.Context) {
+        query := NewQuery(table, primary, replica).WithContext(ctx)
+        _ = query.WherePrimary(1).Update(&query.T.Name, "a")
+        lastDB()
+    }
+
+    ctx := WithReadYourWrites(context.Background(), time.Millisecond*50)
+    if got := get(ctx); got != "replica" {
+        t.Errorf("before write: want replica, got %s", got)
+    }
+    update(ctx)
+    if got := get(ctx); got != "primary" {
+        t.Errorf("in window after write: want primary, got %s", got)
+    }
+    if got := get(context.Background()); got != "replica" {
+        t.Errorf("other context: want replica, got %s", got)
+    }
+    time.Sleep(time.Millisecond * 60)
+    if got := get(ctx); got != "replica" {
+        t.Errorf("after window: want replica, got %s", got)
+    }
+
+    ctx = WithReadYourWrites(context.Background(), 0)
+    update(ctx)
+    time.Sleep(time.Millisecond * 10)
+    if got := get(ctx); got != "primary" {
+        t.Errorf("window 0 after write: want primary, got %s", got)
+    }
+}
+
+func TestReadYourWritesFailedWrite(t *testing.T) {
+    ctx := WithReadYourWrites(context.Background(), 0)
+    primary, _ := newFakeDB()
+    replica, _ := newFakeDB()
+
+    //update without condition not executed
+    query := NewQuery(new(testRow), primary, replica).WithContext(ctx)
+    if res := query.Update(&query.T.Name, "a"); res.Err == nil {
+        t.Fatal("want error of update without condition")
+    }
+    if got := NewQuery(new(testRow), primary, replica).WithContext(ctx).readDB(); got != replica {
+        t.Error("want replica after failed write")
+    }
+}
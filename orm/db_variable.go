@@ -0,0 +1,54 @@
+package orm
+
+import (
+    "database/sql"
+    "strconv"
+    "sync"
+)
+
+var serverVariableCache sync.Map
+
+type serverVariableKey struct {
+    db   *sql.DB
+    name string
+}
+
+//select @@name once per db, like auto_increment_increment
+//read through tx if not nil, no other connection of db needed in transaction
+func getServerVariable(db *sql.DB, tx *sql.Tx, name string) (string, error) {
+    if db == nil && tx == nil {
+        return "", ErrDbNotSelected
+    }
+    key := serverVariableKey{db: db, name: name}
+    if v, ok := serverVariableCache.Load(key); ok {
+        return v.(string), nil
+    }
+
+    var row *sql.Row
+    if tx != nil {
+        row = tx.QueryRow("select @@" + name)
+    } else {
+        row = db.QueryRow("select @@" + name)
+    }
+    var ret sql.NullString
+    err := row.Scan(&ret)
+    if err != nil {
+        return "", err
+    }
+    if db != nil {
+        serverVariableCache.Store(key, ret.String)
+    }
+    return ret.String, nil
+}
+
+func getServerVariableInt(db *sql.DB, tx *sql.Tx, name string, defaultVal int64) int64 {
+    v, err := getServerVariable(db, tx, name)
+    if err != nil {
+        return defaultVal
+    }
+    ret, err := strconv.ParseInt(v, 10, 64)
+    if err != nil || ret <= 0 {
+        return defaultVal
+    }
+    return ret
+}
@@ -0,0 +1,168 @@
+package orm
+
+import (
+    "fmt"
+    "reflect"
+    "time"
+)
+
+//table cached by primary key for Get(id), Gets(ids...), ShardTable not cached
+type EntityCacheTable interface {
+    EntityCacheTTL() time.Duration
+}
+
+var entityCache = NewLRUQueryCache(defaultQueryCacheCapacity)
+
+//max entities cached of all tables, least recently used removed
+func SetEntityCacheCapacity(capacity int) {
+    entityCache = NewLRUQueryCache(capacity)
+}
+
+//ttl of entity cache, false if not cacheable query
+func (q *Query[T]) entityCacheTTL() (time.Duration, bool) {
+    t, ok := q.tables[0].table.(EntityCacheTable)
+    if ok == false || t.EntityCacheTTL() <= 0 || q.result.Err != nil {
+        return 0, false
+    }
+    if _, ok := q.tables[0].table.(ShardTable); ok {
+        //same id in tables of different shards
+        return 0, false
+    }
+    if q.tx != nil || len(q.tables) > 1 || q.tables[0].rawSql != "" || len(q.wheres) > 0 || len(q.columns) > 0 ||
+        len(q.groupBy) > 0 || len(q.having) > 0 || len(q.orderbys) > 0 || len(q.unions) > 0 || len(q.withCtes) > 0 ||
+        len(q.windows) > 0 || q.self != nil || q.prepareSql != "" || q.forUpdate != "" || q.limit > 0 || q.offset > 0 {
+        return 0, false
+    }
+    return t.EntityCacheTTL(), true
+}
+
+func (q *Query[T]) entityCacheTable() string {
+    return q.tables[0].table.DatabaseName() + "." + q.tables[0].table.TableName()
+}
+
+//write db of query (replicas share it), table and id
+func (q *Query[T]) entityCacheKey(id any) string {
+    return fmt.Sprintf("%p|", q.writeDB()) + q.entityCacheTable() + "|" + fmt.Sprint(comparableValue(id))
+}
+
+//rows by primary ids in order of ids, hits from cache, misses loaded by one where id in
+func (q *Query[T]) getsFromEntityCache(ids []any, ttl time.Duration) ([]T, QueryResult) {
+    found := make(map[string]T, len(ids))
+    seen := make(map[string]bool, len(ids))
+    var keys []string
+    var misses []any
+    for _, id := range ids {
+        key := q.entityCacheKey(id)
+        if seen[key] {
+            continue
+        }
+        seen[key] = true
+        keys = append(keys, key)
+        if v, ok := entityCache.Get(key); ok {
+            if row, ok := deepCopyValue(reflect.ValueOf(v)).Interface().(T); ok {
+                found[key] = row
+                continue
+            }
+        }
+        misses = append(misses, id)
+    }
+
+    if len(misses) > 0 {
+        var rows []T
+        res := q.WherePrimary(misses).GetTo(&rows)
+        if res.Err != nil {
+            return nil, res
+        }
+        for _, row := range rows {
+            rowVal := reflect.ValueOf(row)
+            if rowVal.IsNil() {
+                continue
+            }
+            key := q.entityCacheKey(rowVal.Elem().Field(0).Interface())
+            entityCache.Set(key, deepCopyValue(rowVal).Interface(), ttl, q.entityCacheTable())
+            found[key] = row
+        }
+    }
+
+    ret := make([]T, 0, len(keys))
+    for _, key := range keys {
+        if row, ok := found[key]; ok {
+            ret = append(ret, row)
+        }
+    }
+    q.result.RowsAffected = int64(len(ret))
+    return ret, q.result
+}
+
+//ids of Get, Gets, slice expanded
+func entityCacheIds(primaryIds []any) []any {
+    if len(primaryIds) == 1 {
+        val := reflect.ValueOf(primaryIds[0])
+        if val.Kind() == reflect.Slice && val.Type().Elem().Kind() != reflect.Uint8 {
+            ids := make([]any, val.Len())
+            for i := range ids {
+                ids[i] = val.Index(i).Interface()
+            }
+            return ids
+        }
+    }
+    return primaryIds
+}
+
+//evict entities written by statement of kind, by primary ids in where conditions, or whole table
+func (q *Query[T]) evictEntityCache(kind QueryKind) {
+    for k, table := range q.tables {
+        if _, ok := table.table.(EntityCacheTable); ok == false || table.rawSql != "" {
+            continue
+        }
+        name := table.table.DatabaseName() + "." + table.table.TableName()
+        if k > 0 {
+            entityCache.Invalidate(name)
+            continue
+        }
+
+        switch kind {
+        case QueryKindInsert:
+            //plain insert never changes cached rows, upsert may change rows of any unique key
+            if len(q.conflictUpdates) > 0 || q.replace {
+                entityCache.Invalidate(name)
+            }
+        case QueryKindUpdate, QueryKindDelete:
+            ids, ok := q.primaryIdsOfWheres()
+            if ok && len(q.tables) == 1 {
+                for _, id := range ids {
+                    entityCache.Delete(q.entityCacheKey(id))
+                }
+            } else {
+                entityCache.Invalidate(name)
+            }
+        default:
+            entityCache.Invalidate(name)
+        }
+    }
+}
+
+//primary ids of top level primary = or in condition
+func (q *Query[T]) primaryIdsOfWheres() ([]any, bool) {
+    primaryColumn, err := q.parseColumn(q.tables[0].tableStruct.Field(0).Addr().Interface())
+    if err != nil {
+        return nil, false
+    }
+    //rows of or condition unknown
+    for k, v := range q.wheres {
+        if k > 0 && v.IsOr {
+            return nil, false
+        }
+    }
+    for _, v := range q.wheres {
+        if v.Column != primaryColumn || len(v.SubWheres) > 0 {
+            continue
+        }
+        if v.Raw == "" && v.Operator == string(WhereEqual) {
+            return []any{v.Val}, true
+        } else if v.Operator == string(WhereIn) && len(v.RawBindings) > 0 {
+            return v.RawBindings, true
+        }
+    }
+    return nil, false
+}
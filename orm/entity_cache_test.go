@@ -0,0 +1,286 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+//table cached by primary key
+type testCachedRow struct {
+    Id   int    `json:"id"`
+    Name string `json:"name"`
+}
+
+func (*testCachedRow) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testCachedRow) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testCachedRow) TableName() string {
+    return "test_cached_row"
+}
+
+func (*testCachedRow) EntityCacheTTL() time.Duration {
+    return time.Minute
+}
+
+//sharded table with entity cache ttl
+type testCachedOrder struct {
+    Id     int    `json:"id"`
+    UserId int    `json:"user_id"`
+    Status string `json:"status"`
+}
+
+func (*testCachedOrder) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testCachedOrder) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testCachedOrder) TableName() string {
+    return "orders"
+}
+
+func (o *testCachedOrder) ShardKey() any {
+    return &o.UserId
+}
+
+func (*testCachedOrder) ShardStrategy() ShardStrategy {
+    return testOrderShards
+}
+
+func (*testCachedOrder) EntityCacheTTL() time.Duration {
+    return time.Minute
+}
+
+//fake db answering rows of ids bound, selects recorded
+func newEntityCacheDB(t *testing.T) (*sql.DB, *fakeDB) {
+    SetEntityCacheCapacity(100)
+    t.Cleanup(func() {
+        SetEntityCacheCapacity(defaultQueryCacheCapacity)
+    })
+
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        rows := &fakeRows{columns: []string{"id", "name"}}
+        for _, v := range args {
+            if id, ok := v.(int); ok && id < 100 {
+                rows.rows = append(rows.rows, []driver.Value{int64(id), "name" + strings.Repeat("!", id)})
+            }
+        }
+        return rows, nil
+    }
+    return db, fake
+}
+
+//bindings of selects recorded since last call, server variables excluded
+func selectedIds(fake *fakeDB, from *int) [][]any {
+    var ret [][]any
+    statements := fake.recorded()
+    for _, v := range statements[*from:] {
+        if strings.HasPrefix(v.sql, "select") && strings.HasPrefix(v.sql, "select @@") == false {
+            ret = append(ret, v.args)
+        }
+    }
+    *from = len(statements)
+    return ret
+}
+
+func TestEntityCacheGet(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    from := 0
+
+    row, res := NewQuery(table, db).Get(1)
+    if res.Err != nil || row.Id != 1 {
+        t.Fatalf("want row 1, got %+v, %v", row, res.Err)
+    }
+    if got := selectedIds(fake, &from); reflect.DeepEqual(got, [][]any{{1}}) == false {
+        t.Errorf("want row 1 loaded, got %v", got)
+    }
+
+    //copy returned, cache not changed by caller
+    row.Name = "changed"
+    row, res = NewQuery(table, db).Get(1)
+    if res.Err != nil || row.Name != "name!" || res.RowsAffected != 1 {
+        t.Errorf("want cached row 1, got %+v, %v", row, res)
+    }
+    if got := selectedIds(fake, &from); len(got) != 0 {
+        t.Errorf("want cache hit, got %v", got)
+    }
+
+    //missing row not cached
+    row, _ = NewQuery(table, db).Get(100)
+    NewQuery(table, db).Get(100)
+    if row.Id != 0 || len(selectedIds(fake, &from)) != 2 {
+        t.Errorf("want missing row queried each time, got %+v", row)
+    }
+}
+
+func TestEntityCacheGets(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    from := 0
+
+    NewQuery(table, db).Get(2)
+    selectedIds(fake, &from)
+
+    rows, res := NewQuery(table, db).Gets(3, 2, 1, 3, 100)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    var ids []int
+    for _, v := range rows {
+        ids = append(ids, v.Id)
+    }
+    //in order of ids, duplicates once
+    if reflect.DeepEqual(ids, []int{3, 2, 1}) == false || res.RowsAffected != 3 {
+        t.Errorf("want rows [3 2 1], got %v, rows affected %d", ids, res.RowsAffected)
+    }
+    if got := selectedIds(fake, &from); reflect.DeepEqual(got, [][]any{{3, 1, 100}}) == false {
+        t.Errorf("want misses loaded by one select, got %v", got)
+    }
+
+    //slice of ids
+    rows, _ = NewQuery(table, db).Gets([]int{1, 2, 3})
+    if len(rows) != 3 || len(selectedIds(fake, &from)) != 0 {
+        t.Errorf("want all hits, got %d rows", len(rows))
+    }
+}
+
+func TestEntityCacheNotUsed(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    from := 0
+
+    NewQuery(table, db).Get(1)
+    NewQuery(table, db).Where(&table.Name, "name!").Get(1)
+    NewQuery(table, db).Select(&table.Id).Get(1)
+    NewQuery(table, db).Transaction(func(query *Query[*testCachedRow]) error {
+        query.Get(1)
+        return nil
+    })
+    NewQuery(new(testRow), db).Get(1)
+
+    if got := selectedIds(fake, &from); len(got) != 5 {
+        t.Errorf("want cache used by plain Get only, got %d selects", len(got))
+    }
+}
+
+func TestEntityCacheOfDB(t *testing.T) {
+    dbA, fakeA := newEntityCacheDB(t)
+    dbB, fakeB := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    fromA, fromB := 0, 0
+
+    NewQuery(table, dbA).Get(1)
+    NewQuery(table, dbB).Get(1)
+    NewQuery(table, dbA).Get(1)
+    if len(selectedIds(fakeA, &fromA)) != 1 || len(selectedIds(fakeB, &fromB)) != 1 {
+        t.Errorf("want row of each db loaded once")
+    }
+
+    //evicted of written db only
+    NewQuery(table, dbB).Where(&table.Id, 1).Update(&table.Name, "x")
+    NewQuery(table, dbA).Get(1)
+    NewQuery(table, dbB).Get(1)
+    if len(selectedIds(fakeA, &fromA)) != 0 || len(selectedIds(fakeB, &fromB)) != 1 {
+        t.Errorf("want row of db b loaded again only")
+    }
+}
+
+func TestEntityCacheShardTable(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedOrder)
+    from := 0
+
+    NewQuery(table, db).Shard(1).Get(1)
+    NewQuery(table, db).Shard(1).Get(1)
+    NewQuery(table, db).Shard(2).Get(1)
+    if got := selectedIds(fake, &from); len(got) != 3 {
+        t.Errorf("want shard table not cached, got %d selects", len(got))
+    }
+}
+
+func TestEntityCacheEviction(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    from := 0
+
+    load := func() int {
+        NewQuery(table, db).Gets(1, 2, 3)
+        got := selectedIds(fake, &from)
+        if len(got) == 0 {
+            return 0
+        }
+        return len(got[0])
+    }
+
+    tests := []struct {
+        name  string
+        write func()
+        want  int //ids loaded again
+    }{
+        {name: "update by primary id", write: func() { NewQuery(table, db).Where(&table.Id, 2).Update(&table.Name, "x") }, want: 1},
+        {name: "delete by primary ids", write: func() { NewQuery(table, db).Delete(1, 3) }, want: 2},
+        {name: "update by other column", write: func() { NewQuery(table, db).Where(&table.Name, "x").Update(&table.Name, "y") }, want: 3},
+        {name: "update by primary id or other", write: func() {
+            NewQuery(table, db).Where(&table.Id, 2).OrWhere(&table.Name, "x").Update(&table.Name, "y")
+        }, want: 3},
+        {name: "plain insert", write: func() { NewQuery(table, db).Insert(&testCachedRow{Name: "new"}) }, want: 0},
+        {name: "upsert", write: func() {
+            NewQuery(table, db).OnConflictUpdate(&table.Name, &table.Name).Insert(&testCachedRow{Id: 1, Name: "new"})
+        }, want: 3},
+        {name: "raw execute", write: func() { NewQuery(table, db).Raw("truncate table mydb.test_cached_row").Execute() }, want: 3},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            load()
+            tt.write()
+            if got := load(); got != tt.want {
+                t.Errorf("want %d ids loaded after write, got %d", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestEntityCacheTransaction(t *testing.T) {
+    db, fake := newEntityCacheDB(t)
+    table := new(testCachedRow)
+    from := 0
+
+    load := func() int {
+        NewQuery(table, db).Gets(1, 2)
+        return len(selectedIds(fake, &from))
+    }
+    load()
+
+    NewQuery(table, db).Transaction(func(query *Query[*testCachedRow]) error {
+        query.Where(&table.Id, 1).Update(&table.Name, "x")
+        if got := load(); got != 0 {
+            t.Errorf("want cached row before commit, got %d selects", got)
+        }
+        return errors.New("rollback")
+    })
+    if got := load(); got != 0 {
+        t.Errorf("want cached row after rollback, got %d selects", got)
+    }
+
+    NewQuery(table, db).Transaction(func(query *Query[*testCachedRow]) error {
+        return query.Where(&table.Id, 1).Update(&table.Name, "x").Err
+    })
+    if got := load(); got != 1 {
+        t.Errorf("want evicted after commit, got %d selects", got)
+    }
+}
@@ -18,4 +18,10 @@ var (
     ErrInsertPtrNotAllowed              = errors.New("insert ptr data not allowed")
     ErrUpdateWithoutCondition           = errors.New("update without condition not allowed")
     ErrDeleteWithoutCondition           = errors.New("delete without condition not allowed")
+    ErrReplaceWithConflictUpdate        = errors.New("replace with on conflict update not allowed")
+    ErrRowAliasOfSubQuery               = errors.New("row alias of InsertedValue not allowed by insert of subquery")
+    ErrTableNotSharded                  = errors.New("table not sharded")
+    ErrShardNotFound                    = errors.New("shard not found")
+    ErrShardKeyInvalid                  = errors.New("shard key invalid")
+    ErrShardKeyRequired                 = errors.New("shard key required")
 )
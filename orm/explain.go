@@ -0,0 +1,206 @@
+package orm
+
+import (
+    "bytes"
+    "database/sql"
+    "encoding/json"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+//table accessed in query plan
+type PlanTable struct {
+    Table        string
+    AccessType   string //ALL, index, range, ref, eq_ref, const...
+    PossibleKeys []string
+    Key          string //empty if no index used
+    RowsExamined int64  //rows examined per scan
+}
+
+func (p PlanTable) String() string {
+    return p.Table + "(" + p.AccessType + ", key=" + p.Key + ", rows=" + strconv.FormatInt(p.RowsExamined, 10) + ")"
+}
+
+//plan of explain format=json output, tables in order
+func parseExplainJSON(data []byte) (QueryPlan, error) {
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    var root map[string]any
+    if err := decoder.Decode(&root); err != nil {
+        return QueryPlan{}, err
+    }
+
+    plan := QueryPlan{Json: string(data)}
+    if block, ok := root["query_block"].(map[string]any); ok {
+        if costInfo, ok := block["cost_info"].(map[string]any); ok {
+            plan.Cost, _ = strconv.ParseFloat(explainString(costInfo["query_cost"]), 64)
+        }
+    }
+    walkExplainJSON(root, func(key string, node map[string]any) {
+        if node["using_filesort"] == true {
+            plan.UsingFilesort = true
+        }
+        if node["using_temporary_table"] == true {
+            plan.UsingTemporary = true
+        }
+        if key != "table" {
+            return
+        }
+        if _, ok := node["table_name"]; ok == false {
+            return
+        }
+        table := PlanTable{
+            Table:      explainString(node["table_name"]),
+            AccessType: explainString(node["access_type"]),
+            Key:        explainString(node["key"]),
+        }
+        if keys, ok := node["possible_keys"].([]any); ok {
+            for _, v := range keys {
+                table.PossibleKeys = append(table.PossibleKeys, explainString(v))
+            }
+        }
+        if n, ok := node["rows_examined_per_scan"].(json.Number); ok {
+            table.RowsExamined, _ = n.Int64()
+        }
+        plan.Tables = append(plan.Tables, table)
+    })
+    return plan, nil
+}
+
+//visit every object of explain json with its key
+func walkExplainJSON(v any, visit func(key string, node map[string]any)) {
+    switch val := v.(type) {
+    case map[string]any:
+        for _, child := range sortedExplainKeys(val) {
+            if node, ok := val[child].(map[string]any); ok {
+                visit(child, node)
+            }
+            walkExplainJSON(val[child], visit)
+        }
+    case []any:
+        for _, child := range val {
+            walkExplainJSON(child, visit)
+        }
+    }
+}
+
+//keys in stable order, tables of nested_loop keep plan order in array
+func sortedExplainKeys(m map[string]any) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func explainString(v any) string {
+    switch val := v.(type) {
+    case string:
+        return val
+    case nil:
+        return ""
+    case json.Number:
+        return val.String()
+    }
+    return ""
+}
+
+func summarizePlan(tables []PlanTable) string {
+    strs := make([]string, len(tables))
+    for k, v := range tables {
+        strs[k] = v.String()
+    }
+    return strings.Join(strs, "; ")
+}
+
+//plan of select by explain
+type QueryPlan struct {
+    Tables         []PlanTable
+    UsingFilesort  bool
+    UsingTemporary bool
+    Cost           float64 //query_cost
+    Json           string  //output of explain format=json
+    AnalyzeTree    string  //output of explain analyze, by ExplainAnalyze
+}
+
+type PlanIssueKind string
+
+const (
+    PlanIssueFullScan    PlanIssueKind = "full_table_scan"
+    PlanIssueFilesort    PlanIssueKind = "filesort"
+    PlanIssueTemporary   PlanIssueKind = "temporary_table"
+    PlanIssueUnusedIndex PlanIssueKind = "unused_index" //possible keys, none used
+)
+
+type PlanIssue struct {
+    Kind    PlanIssueKind
+    Table   string //empty for filesort, temporary table
+    Message string
+}
+
+//full table scans, filesort, temporary tables, unused indexes of plan
+func (p QueryPlan) Analyze() []PlanIssue {
+    var issues []PlanIssue
+    for _, v := range p.Tables {
+        if strings.EqualFold(v.AccessType, "ALL") {
+            issues = append(issues, PlanIssue{
+                Kind:    PlanIssueFullScan,
+                Table:   v.Table,
+                Message: "full table scan on " + v.Table + ", rows examined " + strconv.FormatInt(v.RowsExamined, 10),
+            })
+        }
+        if v.Key == "" && len(v.PossibleKeys) > 0 {
+            issues = append(issues, PlanIssue{
+                Kind:    PlanIssueUnusedIndex,
+                Table:   v.Table,
+                Message: "possible keys " + strings.Join(v.PossibleKeys, ",") + " of " + v.Table + " not used",
+            })
+        }
+    }
+    if p.UsingFilesort {
+        issues = append(issues, PlanIssue{Kind: PlanIssueFilesort, Message: "using filesort"})
+    }
+    if p.UsingTemporary {
+        issues = append(issues, PlanIssue{Kind: PlanIssueTemporary, Message: "using temporary table"})
+    }
+    return issues
+}
+
+//plan by explain format=json of select, query not executed
+func (q *Query[T]) Explain() (QueryPlan, error) {
+    var data []byte
+    err := q.explain("explain format=json ", &data)
+    if err != nil {
+        return QueryPlan{}, err
+    }
+    return parseExplainJSON(data)
+}
+
+//plan with explain analyze output of mysql 8.0.18+, query executed
+func (q *Query[T]) ExplainAnalyze() (QueryPlan, error) {
+    plan, err := q.Clone().Explain()
+    if err != nil {
+        return plan, err
+    }
+    err = q.explain("explain analyze ", &plan.AnalyzeTree)
+    return plan, err
+}
+
+func (q *Query[T]) explain(prefix string, dest any) error {
+    prepareSql, bindings, err := q.Clone().ToSelectSql()
+    if err != nil {
+        return err
+    }
+
+    var row *sql.Row
+    if q.Tx() != nil {
+        row = q.Tx().QueryRowContext(q.context(), prefix+prepareSql, bindings...)
+    } else if db := q.readDB(); db != nil {
+        row = db.QueryRowContext(q.context(), prefix+prepareSql, bindings...)
+    } else {
+        return ErrDbNotSelected
+    }
+    return row.Scan(dest)
+}
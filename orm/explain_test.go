@@ -0,0 +1,191 @@
+package orm
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseExplainJSON(t *testing.T) {
+    tests := []struct {
+        name string
+        json string
+        want QueryPlan
+    }{
+        {
+            name: "single table",
+            json: `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.20"},
+                "table": {"table_name": "user", "access_type": "const", "possible_keys": ["PRIMARY"], "key": "PRIMARY", "rows_examined_per_scan": 1}}}`,
+            want: QueryPlan{
+                Tables: []PlanTable{{Table: "user", AccessType: "const", PossibleKeys: []string{"PRIMARY"}, Key: "PRIMARY", RowsExamined: 1}},
+                Cost:   1.2,
+            },
+        },
+        {
+            name: "nested loop in order",
+            json: `{"query_block": {"cost_info": {"query_cost": "35.50"}, "nested_loop": [
+                {"table": {"table_name": "order", "access_type": "ALL", "rows_examined_per_scan": 100}},
+                {"table": {"table_name": "user", "access_type": "eq_ref", "possible_keys": ["PRIMARY"], "key": "PRIMARY", "rows_examined_per_scan": 1}}]}}`,
+            want: QueryPlan{
+                Tables: []PlanTable{
+                    {Table: "order", AccessType: "ALL", RowsExamined: 100},
+                    {Table: "user", AccessType: "eq_ref", PossibleKeys: []string{"PRIMARY"}, Key: "PRIMARY", RowsExamined: 1},
+                },
+                Cost: 35.5,
+            },
+        },
+        {
+            name: "filesort and temporary",
+            json: `{"query_block": {"cost_info": {"query_cost": "10"}, "ordering_operation": {"using_filesort": true,
+                "grouping_operation": {"using_temporary_table": true,
+                "table": {"table_name": "order", "access_type": "index", "possible_keys": ["idx_user"], "rows_examined_per_scan": 50}}}}}`,
+            want: QueryPlan{
+                Tables:         []PlanTable{{Table: "order", AccessType: "index", PossibleKeys: []string{"idx_user"}, RowsExamined: 50}},
+                UsingFilesort:  true,
+                UsingTemporary: true,
+                Cost:           10,
+            },
+        },
+        {
+            name: "no table",
+            json: `{"query_block": {"select_id": 1, "message": "No tables used"}}`,
+            want: QueryPlan{},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := parseExplainJSON([]byte(tt.json))
+            if err != nil {
+                t.Fatal(err)
+            }
+            tt.want.Json = tt.json
+            if reflect.DeepEqual(got, tt.want) == false {
+                t.Errorf("want %+v, got %+v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestParseExplainJSONInvalid(t *testing.T) {
+    if _, err := parseExplainJSON([]byte("explain output")); err == nil {
+        t.Error("want error")
+    }
+}
+
+func TestAnalyze(t *testing.T) {
+    tests := []struct {
+        name string
+        plan QueryPlan
+        want []PlanIssueKind
+    }{
+        {
+            name: "index used",
+            plan: QueryPlan{Tables: []PlanTable{{Table: "user", AccessType: "const", PossibleKeys: []string{"PRIMARY"}, Key: "PRIMARY"}}},
+        },
+        {
+            name: "full table scan",
+            plan: QueryPlan{Tables: []PlanTable{{Table: "user", AccessType: "ALL", RowsExamined: 1000}}},
+            want: []PlanIssueKind{PlanIssueFullScan},
+        },
+        {
+            name: "possible keys not used",
+            plan: QueryPlan{Tables: []PlanTable{{Table: "user", AccessType: "all", PossibleKeys: []string{"idx_name"}}}},
+            want: []PlanIssueKind{PlanIssueFullScan, PlanIssueUnusedIndex},
+        },
+        {
+            name: "filesort and temporary",
+            plan: QueryPlan{
+                Tables:         []PlanTable{{Table: "order", AccessType: "index", Key: "idx_user"}},
+                UsingFilesort:  true,
+                UsingTemporary: true,
+            },
+            want: []PlanIssueKind{PlanIssueFilesort, PlanIssueTemporary},
+        },
+        {
+            name: "tables in order",
+            plan: QueryPlan{Tables: []PlanTable{
+                {Table: "order", AccessType: "ALL"},
+                {Table: "user", AccessType: "ref", PossibleKeys: []string{"PRIMARY"}},
+            }},
+            want: []PlanIssueKind{PlanIssueFullScan, PlanIssueUnusedIndex},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var got []PlanIssueKind
+            for _, v := range tt.plan.Analyze() {
+                got = append(got, v.Kind)
+                if v.Message == "" {
+                    t.Errorf("%s: empty message", v.Kind)
+                }
+            }
+            if reflect.DeepEqual(got, tt.want) == false {
+                t.Errorf("want %v, got %v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestAnalyzeIssueTable(t *testing.T) {
+    plan := QueryPlan{Tables: []PlanTable{{Table: "order", AccessType: "ALL", RowsExamined: 100}}, UsingFilesort: true}
+    issues := plan.Analyze()
+    if len(issues) != 2 || issues[0].Table != "order" || issues[1].Table != "" {
+        t.Errorf("got %+v", issues)
+    }
+    if want := "full table scan on order, rows examined 100"; issues[0].Message != want {
+        t.Errorf("want %q, got %q", want, issues[0].Message)
+    }
+}
+
+func TestSummarizePlan(t *testing.T) {
+    got := summarizePlan([]PlanTable{
+        {Table: "order", AccessType: "ALL", RowsExamined: 100},
+        {Table: "user", AccessType: "eq_ref", Key: "PRIMARY", RowsExamined: 1},
+    })
+    want := "order(ALL, key=, rows=100); user(eq_ref, key=PRIMARY, rows=1)"
+    if got != want {
+        t.Errorf("want %s, got %s", want, got)
+    }
+}
+
+func TestExplain(t *testing.T) {
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        if strings.HasPrefix(sqlStr, "explain analyze ") {
+            return fakeValueRows("EXPLAIN", "-> Table scan on test_row  (actual time=0.1..0.2 rows=3 loops=1)"), nil
+        }
+        return fakeValueRows("EXPLAIN", `{"query_block": {"cost_info": {"query_cost": "0.55"},
+            "table": {"table_name": "test_row", "access_type": "ALL", "rows_examined_per_scan": 3}}}`), nil
+    }
+    table := new(testRow)
+
+    plan, err := NewQuery(table, db).Where(&table.Name, "john").ExplainAnalyze()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(plan.Tables) != 1 || plan.Tables[0].AccessType != "ALL" || plan.Cost != 0.55 {
+        t.Errorf("want plan of test_row, got %+v", plan)
+    }
+    if strings.HasPrefix(plan.AnalyzeTree, "-> Table scan on test_row") == false {
+        t.Errorf("want analyze tree, got %s", plan.AnalyzeTree)
+    }
+    if issues := plan.Analyze(); len(issues) != 1 || issues[0].Kind != PlanIssueFullScan {
+        t.Errorf("want full scan issue, got %+v", issues)
+    }
+
+    want := []string{
+        "explain format=json select * from mydb.test_row where mydb.test_row.`name` = ?",
+        "explain analyze select * from mydb.test_row where mydb.test_row.`name` = ?",
+    }
+    statements := fake.recorded()
+    if len(statements) != 2 {
+        t.Fatalf("want 2 statements, got %v", statements)
+    }
+    for k, v := range statements {
+        if strings.TrimSpace(v.sql) != want[k] || reflect.DeepEqual(v.args, []any{"john"}) == false {
+            t.Errorf("want %s [john], got %s %v", want[k], v.sql, v.args)
+        }
+    }
+}
@@ -0,0 +1,191 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "io"
+    "sync"
+)
+
+//table of tests
+type testRow struct {
+    Id   int    `json:"id"`
+    Name string `json:"name"`
+    Data string `json:"data"`
+}
+
+func (*testRow) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testRow) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testRow) TableName() string {
+    return "test_row"
+}
+
+//statement received by fake db
+type fakeStatement struct {
+    sql  string
+    args []any
+}
+
+//db of tests without mysql server, records statements, answers by exec and query funcs
+//empty result and rows if funcs not set
+type fakeDB struct {
+    mu         sync.Mutex
+    statements []fakeStatement
+    exec       func(sqlStr string, args []any) (driver.Result, error)
+    query      func(sqlStr string, args []any) (*fakeRows, error)
+    pingErr    error
+    txs        []string //begin, commit, rollback in order
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+    f := &fakeDB{}
+    return sql.OpenDB(f), f
+}
+
+func (f *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+    return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+    return f
+}
+
+func (f *fakeDB) Open(name string) (driver.Conn, error) {
+    return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) record(sqlStr string, args []driver.NamedValue) []any {
+    values := make([]any, len(args))
+    for k, v := range args {
+        values[k] = v.Value
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.statements = append(f.statements, fakeStatement{sql: sqlStr, args: values})
+    return values
+}
+
+func (f *fakeDB) recorded() []fakeStatement {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]fakeStatement{}, f.statements...)
+}
+
+func (f *fakeDB) recordTx(event string) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.txs = append(f.txs, event)
+    return nil
+}
+
+func (f *fakeDB) transactions() []string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]string{}, f.txs...)
+}
+
+func (f *fakeDB) setPingErr(err error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.pingErr = err
+}
+
+type fakeConn struct {
+    db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+    return nil, errors.New("fake db: prepared statement not supported")
+}
+
+func (c *fakeConn) Close() error {
+    return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+    return c, c.db.recordTx("begin")
+}
+
+func (c *fakeConn) Commit() error {
+    return c.db.recordTx("commit")
+}
+
+func (c *fakeConn) Rollback() error {
+    return c.db.recordTx("rollback")
+}
+
+//any binding accepted as it is
+func (c *fakeConn) CheckNamedValue(v *driver.NamedValue) error {
+    return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    values := c.db.record(query, args)
+    if c.db.exec == nil {
+        return driver.RowsAffected(0), nil
+    }
+    return c.db.exec(query, values)
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    values := c.db.record(query, args)
+    if c.db.query == nil {
+        return &fakeRows{}, nil
+    }
+    return c.db.query(query, values)
+}
+
+type fakeRows struct {
+    columns []string
+    rows    [][]driver.Value
+    index   int
+}
+
+func (r *fakeRows) Columns() []string {
+    return r.columns
+}
+
+func (r *fakeRows) Close() error {
+    return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+    if r.index >= len(r.rows) {
+        return io.EOF
+    }
+    copy(dest, r.rows[r.index])
+    r.index++
+    return nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+    c.db.mu.Lock()
+    defer c.db.mu.Unlock()
+    return c.db.pingErr
+}
+
+type fakeResult struct {
+    lastInsertId int64
+    rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+    return r.lastInsertId, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+    return r.rowsAffected, nil
+}
+
+//rows of one column and one row, like select @@version
+func fakeValueRows(column string, value driver.Value) *fakeRows {
+    return &fakeRows{columns: []string{column}, rows: [][]driver.Value{{value}}}
+}
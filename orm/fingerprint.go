@@ -0,0 +1,154 @@
+package orm
+
+import (
+    "strings"
+)
+
+//normalized sql shape: comments removed, literals replaced by ?,
+//in lists and multi-row values collapsed to (?+), lower case, single spaces
+//select * from user where id in (1,2,3) and name = 'a' => select * from user where id in (?+) and name = ?
+func Fingerprint(sql string) string {
+    var str strings.Builder
+    str.Grow(len(sql))
+
+    space := false
+    writeSpace := func() {
+        if space && str.Len() > 0 {
+            str.WriteByte(' ')
+        }
+        space = false
+    }
+
+    for i := 0; i < len(sql); i++ {
+        c := sql[i]
+        switch {
+        case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+            space = true
+        case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+            end := strings.Index(sql[i+2:], "*/")
+            if end < 0 {
+                i = len(sql)
+            } else {
+                i += end + 3
+            }
+            space = true
+        case c == '#' || (c == '-' && i+2 < len(sql) && sql[i+1] == '-' && sql[i+2] == ' '):
+            end := strings.IndexByte(sql[i:], '\n')
+            if end < 0 {
+                i = len(sql)
+            } else {
+                i += end
+            }
+            space = true
+        case c == '\'' || c == '"':
+            i = skipQuoted(sql, i, c)
+            writeSpace()
+            str.WriteByte('?')
+        case c == '`':
+            end := strings.IndexByte(sql[i+1:], '`')
+            if end < 0 {
+                end = len(sql)
+            } else {
+                end += i + 2
+            }
+            writeSpace()
+            str.WriteString(sql[i:end])
+            i = end - 1
+        case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+            if str.Len() > 0 && space == false && isIdentByte(lastByte(&str)) {
+                //part of identifier, like t1
+                str.WriteByte(c)
+                continue
+            }
+            for i+1 < len(sql) && (isIdentByte(sql[i+1]) || sql[i+1] == '.') {
+                i++
+            }
+            writeSpace()
+            str.WriteByte('?')
+        default:
+            writeSpace()
+            if c >= 'A' && c <= 'Z' {
+                c += 'a' - 'A'
+            }
+            str.WriteByte(c)
+        }
+    }
+    return collapseFingerprintLists(strings.TrimRight(str.String(), "; "))
+}
+
+//end index of quoted string starting at i, quotes escaped by \ or doubled
+func skipQuoted(sql string, i int, quote byte) int {
+    for j := i + 1; j < len(sql); j++ {
+        if sql[j] == '\\' {
+            j++
+        } else if sql[j] == quote {
+            if j+1 < len(sql) && sql[j+1] == quote {
+                j++
+                continue
+            }
+            return j
+        }
+    }
+    return len(sql) - 1
+}
+
+//(?,?,?) => (?+), (?+),(?+) => (?+)
+func collapseFingerprintLists(s string) string {
+    var str strings.Builder
+    str.Grow(len(s))
+    for i := 0; i < len(s); i++ {
+        if s[i] == '(' {
+            if end, ok := placeholderListEnd(s, i); ok {
+                //skip following lists of values rows
+                str.WriteString("(?+)")
+                i = end
+                for {
+                    j := i + 1
+                    for j < len(s) && (s[j] == ',' || s[j] == ' ') {
+                        j++
+                    }
+                    if j > i+1 && j < len(s) && s[j] == '(' && strings.Contains(s[i+1:j], ",") {
+                        if next, ok := placeholderListEnd(s, j); ok {
+                            i = next
+                            continue
+                        }
+                    }
+                    break
+                }
+                continue
+            }
+        }
+        str.WriteByte(s[i])
+    }
+    return str.String()
+}
+
+//index of ')' if s[start:] is a list of only placeholders
+func placeholderListEnd(s string, start int) (int, bool) {
+    found := false
+    for j := start + 1; j < len(s); j++ {
+        switch s[j] {
+        case '?':
+            found = true
+        case ',', ' ':
+        case ')':
+            return j, found
+        default:
+            return 0, false
+        }
+    }
+    return 0, false
+}
+
+func isDigit(c byte) bool {
+    return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+    return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func lastByte(str *strings.Builder) byte {
+    s := str.String()
+    return s[len(s)-1]
+}
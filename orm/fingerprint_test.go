@@ -0,0 +1,37 @@
+package orm
+
+import (
+    "testing"
+)
+
+func TestFingerprint(t *testing.T) {
+    tests := []struct {
+        name string
+        sql  string
+        want string
+    }{
+        {name: "literals", sql: "select * from user where id = 1 and name = 'john'", want: "select * from user where id = ? and name = ?"},
+        {name: "placeholders", sql: "select * from user where id = ?", want: "select * from user where id = ?"},
+        {name: "in list", sql: "select * from user where id in (1, 2, 3)", want: "select * from user where id in (?+)"},
+        {name: "in placeholders", sql: "select * from user where id in (?,?)", want: "select * from user where id in (?+)"},
+        {name: "values rows", sql: "insert into user (`id`,`name`) values (?,?),(?,?), (?,?);", want: "insert into user (`id`,`name`) values (?+)"},
+        {name: "case and spaces", sql: "SELECT  *\n\tFROM user  WHERE Id = 1", want: "select * from user where id = ?"},
+        {name: "backquoted kept", sql: "select `Name1` from `User` where `id` = 2", want: "select `Name1` from `User` where `id` = ?"},
+        {name: "identifier digits", sql: "select t1.id from t1 join t2 on t1.id = t2.id", want: "select t1.id from t1 join t2 on t1.id = t2.id"},
+        {name: "numbers", sql: "select * from t where a > -1.5 and b < .5 and c = 1e3 and d = 0x1f", want: "select * from t where a > -? and b < ? and c = ? and d = ?"},
+        {name: "escaped quotes", sql: `select * from t where a = 'it\'s' and b = "say ""hi""" and c = 'x''y'`, want: "select * from t where a = ? and b = ? and c = ?"},
+        {name: "block comment", sql: "select * from t /* controller='user' */ where a = 1", want: "select * from t where a = ?"},
+        {name: "trailing comment", sql: "select * from t where a = 1 /*traceparent='00-abc'*/;", want: "select * from t where a = ?"},
+        {name: "line comments", sql: "select * -- all\nfrom t # table\nwhere a = 1", want: "select * from t where a = ?"},
+        {name: "function args kept", sql: "select count(*), ifnull(a, 0) from t", want: "select count(*), ifnull(a, ?) from t"},
+        {name: "empty", sql: "", want: ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Fingerprint(tt.sql); got != tt.want {
+                t.Errorf("\nwant: %s\ngot:  %s", tt.want, got)
+            }
+        })
+    }
+}
@@ -1,5 +1,13 @@
 package orm
 
+import (
+    "context"
+    "runtime"
+    "strconv"
+    "strings"
+    "time"
+)
+
 type InfoLogger interface {
     Info(args ...any)
 }
@@ -20,3 +28,111 @@ func SetErrorLogger(l ErrorLogger) {
     errorLogger = l
     errorLogger.Error("set error logger")
 }
+
+//same values as slog.Level
+type LogLevel int
+
+const (
+    LogLevelDebug LogLevel = -4
+    LogLevelInfo  LogLevel = 0
+    LogLevelWarn  LogLevel = 4
+    LogLevelError LogLevel = 8
+    LogLevelOff   LogLevel = 1 << 10 //not logged
+)
+
+//log with key value pairs, *slog.Logger implements it
+type StructuredLogger interface {
+    DebugContext(ctx context.Context, msg string, args ...any)
+    InfoContext(ctx context.Context, msg string, args ...any)
+    WarnContext(ctx context.Context, msg string, args ...any)
+    ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+//levels of statements logged by StructuredLogger
+type LogLevels struct {
+    Statement LogLevel //succeeded statement, LogLevelDebug by default
+    Error     LogLevel //failed statement, LogLevelError by default
+    Slow      LogLevel //slow select, LogLevelWarn by default
+}
+
+var structuredLogger StructuredLogger
+var logLevels = LogLevels{Statement: LogLevelDebug, Error: LogLevelError, Slow: LogLevelWarn}
+
+//log every statement with fields:
+//sql, duration, rows, table, operation, db_role, tx, caller, error
+func SetStructuredLogger(l StructuredLogger) {
+    structuredLogger = l
+}
+
+func SetLogLevels(levels LogLevels) {
+    logLevels = levels
+}
+
+func logStatement(ctx context.Context, info QueryInfo, res QueryResult, err error, duration time.Duration) {
+    level := logLevels.Statement
+    if err != nil {
+        level = logLevels.Error
+    }
+    if structuredLogger == nil || level >= LogLevelOff {
+        return
+    }
+
+    if res.PrepareSql == "" {
+        res.PrepareSql, res.Bindings = info.Sql, info.Bindings
+    }
+    args := []any{
+        "sql", res.Sql(),
+        "duration", duration,
+        "rows", res.RowsAffected,
+        "table", info.Table,
+        "operation", string(info.Kind),
+        "db_role", string(info.Role),
+        "tx", info.InTx,
+        "caller", callerOutsideOrm(),
+    }
+    msg := "orm query"
+    if err != nil {
+        msg = "orm query failed"
+        args = append(args, "error", err.Error())
+    }
+    logWithLevel(ctx, level, msg, args...)
+}
+
+func logWithLevel(ctx context.Context, level LogLevel, msg string, args ...any) {
+    switch {
+    case level >= LogLevelError:
+        structuredLogger.ErrorContext(ctx, msg, args...)
+    case level >= LogLevelWarn:
+        structuredLogger.WarnContext(ctx, msg, args...)
+    case level >= LogLevelInfo:
+        structuredLogger.InfoContext(ctx, msg, args...)
+    default:
+        structuredLogger.DebugContext(ctx, msg, args...)
+    }
+}
+
+const ormPackagePrefix = "github.com/folospace/go-mysql-orm/orm."
+
+//file:line of first caller not in orm package
+func callerOutsideOrm() string {
+    frame := callerFrameOutsideOrm()
+    if frame.File == "" {
+        return ""
+    }
+    return frame.File + ":" + strconv.Itoa(frame.Line)
+}
+
+func callerFrameOutsideOrm() runtime.Frame {
+    pcs := make([]uintptr, 32)
+    n := runtime.Callers(3, pcs)
+    frames := runtime.CallersFrames(pcs[:n])
+    for {
+        frame, more := frames.Next()
+        if strings.HasPrefix(frame.Function, ormPackagePrefix) == false && strings.HasPrefix(frame.Function, "runtime.") == false {
+            return frame
+        }
+        if more == false {
+            return runtime.Frame{}
+        }
+    }
+}
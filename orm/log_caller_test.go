@@ -0,0 +1,14 @@
+package orm_test
+
+import (
+    "github.com/folospace/go-mysql-orm/orm"
+    "strings"
+    "testing"
+)
+
+func TestCallerOutsideOrm(t *testing.T) {
+    caller := orm.CallerOutsideOrm()
+    if strings.Contains(caller, "log_caller_test.go:10") == false {
+        t.Errorf("want caller log_caller_test.go:10, got %s", caller)
+    }
+}
@@ -0,0 +1,182 @@
+package orm
+
+import (
+    "context"
+    "database/sql/driver"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+)
+
+//entry logged by testLogger
+type testLogEntry struct {
+    level  LogLevel
+    msg    string
+    fields map[string]any
+}
+
+//structured logger keeping entries, like slog with a memory handler
+type testLogger struct {
+    mu      sync.Mutex
+    entries []testLogEntry
+}
+
+func (l *testLogger) log(level LogLevel, msg string, args []any) {
+    fields := map[string]any{}
+    for i := 0; i+1 < len(args); i += 2 {
+        fields[args[i].(string)] = args[i+1]
+    }
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.entries = append(l.entries, testLogEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *testLogger) DebugContext(ctx context.Context, msg string, args ...any) {
+    l.log(LogLevelDebug, msg, args)
+}
+
+func (l *testLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+    l.log(LogLevelInfo, msg, args)
+}
+
+func (l *testLogger) WarnContext(ctx context.Context, msg string, args ...any) {
+    l.log(LogLevelWarn, msg, args)
+}
+
+func (l *testLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+    l.log(LogLevelError, msg, args)
+}
+
+func (l *testLogger) logged() []testLogEntry {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return append([]testLogEntry{}, l.entries...)
+}
+
+//structured logger and levels during test
+func withStructuredLogger(t *testing.T, levels LogLevels) *testLogger {
+    l := &testLogger{}
+    oldLogger, oldLevels := structuredLogger, logLevels
+    SetStructuredLogger(l)
+    SetLogLevels(levels)
+    t.Cleanup(func() {
+        structuredLogger, logLevels = oldLogger, oldLevels
+    })
+    return l
+}
+
+func TestStructuredLogger(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelInfo, Error: LogLevelWarn})
+
+    db, fake := newFakeDB()
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        if strings.HasPrefix(sqlStr, "delete") {
+            return nil, errors.New("lock wait timeout")
+        }
+        return fakeResult{rowsAffected: 2}, nil
+    }
+    table := new(testRow)
+
+    NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+    NewQuery(table, db).Delete(1)
+
+    entries := l.logged()
+    if len(entries) != 2 {
+        t.Fatalf("want 2 entries, got %d", len(entries))
+    }
+
+    update := entries[0]
+    if update.level != LogLevelInfo || update.msg != "orm query" {
+        t.Errorf("want info orm query, got %v %s", update.level, update.msg)
+    }
+    want := map[string]any{
+        "sql":       "update mydb.test_row set mydb.test_row.`name` = 'john' where mydb.test_row.`id` = 1",
+        "rows":      int64(2),
+        "table":     "mydb.test_row",
+        "operation": "update",
+        "db_role":   "primary",
+        "tx":        false,
+    }
+    for k, v := range want {
+        if update.fields[k] != v {
+            t.Errorf("want %s %v, got %v", k, v, update.fields[k])
+        }
+    }
+    if _, ok := update.fields["error"]; ok {
+        t.Errorf("want no error field, got %v", update.fields["error"])
+    }
+
+    //tests are in orm package, caller found outside, see log_caller_test.go
+    if caller, _ := update.fields["caller"].(string); strings.Contains(caller, ".go:") == false {
+        t.Errorf("want caller file:line, got %s", caller)
+    }
+
+    failed := entries[1]
+    if failed.level != LogLevelWarn || failed.msg != "orm query failed" {
+        t.Errorf("want warn orm query failed, got %v %s", failed.level, failed.msg)
+    }
+    if failed.fields["error"] != "lock wait timeout" || failed.fields["operation"] != "delete" {
+        t.Errorf("want delete error field, got %v", failed.fields)
+    }
+}
+
+func TestStructuredLoggerLevels(t *testing.T) {
+    tests := []struct {
+        name   string
+        levels LogLevels
+        err    error
+        want   []LogLevel
+    }{
+        {name: "default debug", levels: LogLevels{Statement: LogLevelDebug, Error: LogLevelError}, want: []LogLevel{LogLevelDebug}},
+        {name: "statement off", levels: LogLevels{Statement: LogLevelOff, Error: LogLevelError}},
+        {name: "error", levels: LogLevels{Statement: LogLevelOff, Error: LogLevelError}, err: errors.New("bad"), want: []LogLevel{LogLevelError}},
+        {name: "error off", levels: LogLevels{Statement: LogLevelInfo, Error: LogLevelOff}, err: errors.New("bad")},
+        {name: "between levels", levels: LogLevels{Statement: LogLevelWarn + 1, Error: LogLevelError}, want: []LogLevel{LogLevelWarn}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            l := withStructuredLogger(t, tt.levels)
+            db, fake := newFakeDB()
+            fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+                return &fakeRows{}, tt.err
+            }
+            NewQuery(new(testRow), db).Gets()
+
+            var got []LogLevel
+            for _, v := range l.logged() {
+                got = append(got, v.level)
+            }
+            if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+                t.Errorf("want %v, got %v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestStructuredLoggerReplica(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelInfo, Error: LogLevelError})
+
+    primary, _ := newFakeDB()
+    replica, _ := newFakeDB()
+    NewQuery(new(testRow), primary, replica).Gets()
+    NewQuery(new(testRow), primary, replica).Transaction(func(query *Query[*testRow]) error {
+        _, res := query.Gets()
+        return res.Err
+    })
+
+    entries := l.logged()
+    if len(entries) != 2 {
+        t.Fatalf("want 2 entries, got %d", len(entries))
+    }
+    if entries[0].fields["db_role"] != "replica" || entries[0].fields["tx"] != false {
+        t.Errorf("want read on replica, got %v", entries[0].fields)
+    }
+    if entries[1].fields["db_role"] != "primary" || entries[1].fields["tx"] != true {
+        t.Errorf("want read in tx on primary, got %v", entries[1].fields)
+    }
+}
+
+//for log_caller_test.go, outside orm package
+var CallerOutsideOrm = callerOutsideOrm
@@ -0,0 +1,242 @@
+package orm
+
+import (
+    "bufio"
+    "database/sql"
+    "expvar"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+//upper bounds of latency histogram in seconds
+var MetricsBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var metricsEnabled bool
+var metrics = &metricsRegistry{series: make(map[metricsKey]*metricsSeries)}
+
+type metricsKey struct {
+    table     string
+    operation QueryKind
+}
+
+type metricsSeries struct {
+    count   int64
+    errors  int64
+    sum     float64
+    buckets []int64
+}
+
+type metricsDB struct {
+    database string
+    index    int //index in connections of table
+    pool     int //sequence of db, unique per *sql.DB
+    db       *sql.DB
+}
+
+type metricsRegistry struct {
+    mu     sync.Mutex
+    series map[metricsKey]*metricsSeries
+    dbs    []metricsDB
+    known  map[*sql.DB]bool
+}
+
+//collect counters and latency histograms per table and operation, and sql.DBStats of connections
+func EnableMetrics(enable bool) {
+    metricsEnabled = enable
+}
+
+func (q *Query[T]) recordMetrics(info QueryInfo, err error, duration time.Duration) {
+    if metricsEnabled == false {
+        return
+    }
+    database := ""
+    if len(q.tables) > 0 {
+        database = q.tables[0].table.DatabaseName()
+    }
+    metrics.record(info, err, duration, database, q.DBs())
+}
+
+func (m *metricsRegistry) record(info QueryInfo, err error, duration time.Duration, database string, dbs []*sql.DB) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    key := metricsKey{table: info.Table, operation: info.Kind}
+    s := m.series[key]
+    if s == nil {
+        s = &metricsSeries{buckets: make([]int64, len(MetricsBuckets))}
+        m.series[key] = s
+    }
+    seconds := duration.Seconds()
+    s.count++
+    s.sum += seconds
+    if err != nil {
+        s.errors++
+    }
+    for k, v := range MetricsBuckets {
+        if seconds <= v && k < len(s.buckets) {
+            s.buckets[k]++
+        }
+    }
+
+    if m.known == nil {
+        m.known = make(map[*sql.DB]bool)
+    }
+    for k, db := range dbs {
+        if db != nil && m.known[db] == false {
+            m.known[db] = true
+            m.dbs = append(m.dbs, metricsDB{database: database, index: k, pool: len(m.dbs) + 1, db: db})
+        }
+    }
+}
+
+//prometheus text format
+func MetricsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+        _ = WriteMetrics(w)
+    })
+}
+
+//write metrics in prometheus text format
+func WriteMetrics(writer io.Writer) error {
+    w := bufio.NewWriter(writer)
+    keys, series, dbs := metrics.snapshot()
+
+    w.WriteString("# HELP orm_queries_total Statements executed by table and operation.\n")
+    w.WriteString("# TYPE orm_queries_total counter\n")
+    for k, key := range keys {
+        labels := metricsLabels("table", key.table, "operation", string(key.operation))
+        w.WriteString("orm_queries_total{" + labels + "} " + strconv.FormatInt(series[k].count, 10) + "\n")
+    }
+
+    w.WriteString("# HELP orm_query_errors_total Statements failed by table and operation.\n")
+    w.WriteString("# TYPE orm_query_errors_total counter\n")
+    for k, key := range keys {
+        labels := metricsLabels("table", key.table, "operation", string(key.operation))
+        w.WriteString("orm_query_errors_total{" + labels + "} " + strconv.FormatInt(series[k].errors, 10) + "\n")
+    }
+
+    w.WriteString("# HELP orm_query_duration_seconds Statement latency by table and operation.\n")
+    w.WriteString("# TYPE orm_query_duration_seconds histogram\n")
+    for k, key := range keys {
+        labels := metricsLabels("table", key.table, "operation", string(key.operation))
+        s := series[k]
+        for i, v := range MetricsBuckets {
+            if i < len(s.buckets) {
+                w.WriteString("orm_query_duration_seconds_bucket{" + labels + ",le=\"" + formatMetricsFloat(v) + "\"} " + strconv.FormatInt(s.buckets[i], 10) + "\n")
+            }
+        }
+        w.WriteString("orm_query_duration_seconds_bucket{" + labels + ",le=\"+Inf\"} " + strconv.FormatInt(s.count, 10) + "\n")
+        w.WriteString("orm_query_duration_seconds_sum{" + labels + "} " + formatMetricsFloat(s.sum) + "\n")
+        w.WriteString("orm_query_duration_seconds_count{" + labels + "} " + strconv.FormatInt(s.count, 10) + "\n")
+    }
+
+    stats := make([]sql.DBStats, len(dbs))
+    for k, v := range dbs {
+        stats[k] = v.db.Stats()
+    }
+    for _, v := range dbStatsMetrics {
+        w.WriteString("# HELP " + v.name + " " + v.help + "\n")
+        w.WriteString("# TYPE " + v.name + " " + v.kind + "\n")
+        for k, db := range dbs {
+            labels := metricsLabels("database", db.database, "index", strconv.Itoa(db.index), "pool", strconv.Itoa(db.pool))
+            w.WriteString(v.name + "{" + labels + "} " + formatMetricsFloat(v.value(stats[k])) + "\n")
+        }
+    }
+    return w.Flush()
+}
+
+var dbStatsMetrics = []struct {
+    name  string
+    help  string
+    kind  string
+    value func(s sql.DBStats) float64
+}{
+    {"orm_db_max_open_connections", "Maximum number of open connections.", "gauge", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+    {"orm_db_open_connections", "Established connections, in use and idle.", "gauge", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+    {"orm_db_in_use_connections", "Connections currently in use.", "gauge", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+    {"orm_db_idle_connections", "Idle connections.", "gauge", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+    {"orm_db_wait_count_total", "Connections waited for.", "counter", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+    {"orm_db_wait_duration_seconds_total", "Time blocked waiting for connections.", "counter", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+    {"orm_db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+    {"orm_db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+    {"orm_db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", "counter", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+//publish metrics to expvar by name, like "orm", should be called once
+func PublishExpvar(name string) {
+    expvar.Publish(name, expvar.Func(func() any {
+        keys, series, dbs := metrics.snapshot()
+
+        queries := make(map[string]any, len(keys))
+        for k, key := range keys {
+            s := series[k]
+            buckets := make(map[string]int64, len(s.buckets))
+            for i, v := range MetricsBuckets {
+                if i < len(s.buckets) {
+                    buckets[formatMetricsFloat(v)] = s.buckets[i]
+                }
+            }
+            queries[key.table+":"+string(key.operation)] = map[string]any{
+                "count":           s.count,
+                "errors":          s.errors,
+                "seconds":         s.sum,
+                "seconds_buckets": buckets,
+            }
+        }
+
+        connections := make(map[string]any, len(dbs))
+        for _, v := range dbs {
+            connections[v.database+":"+strconv.Itoa(v.index)+":"+strconv.Itoa(v.pool)] = v.db.Stats()
+        }
+        return map[string]any{"queries": queries, "connections": connections}
+    }))
+}
+
+//copied series sorted by table and operation
+func (m *metricsRegistry) snapshot() ([]metricsKey, []metricsSeries, []metricsDB) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    keys := make([]metricsKey, 0, len(m.series))
+    for k := range m.series {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i].table != keys[j].table {
+            return keys[i].table < keys[j].table
+        }
+        return keys[i].operation < keys[j].operation
+    })
+
+    series := make([]metricsSeries, len(keys))
+    for k, v := range keys {
+        s := *m.series[v]
+        s.buckets = append([]int64{}, s.buckets...)
+        series[k] = s
+    }
+    return keys, series, append([]metricsDB{}, m.dbs...)
+}
+
+//name="value" pairs
+func metricsLabels(pairs ...string) string {
+    var str strings.Builder
+    for i := 0; i+1 < len(pairs); i += 2 {
+        if i > 0 {
+            str.WriteString(",")
+        }
+        str.WriteString(pairs[i] + "=\"")
+        str.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1]))
+        str.WriteString("\"")
+    }
+    return str.String()
+}
+
+func formatMetricsFloat(v float64) string {
+    return strconv.FormatFloat(v, 'g', -1, 64)
+}
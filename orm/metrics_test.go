@@ -0,0 +1,130 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+    buckets, registry := MetricsBuckets, metrics
+    defer func() {
+        MetricsBuckets, metrics = buckets, registry
+    }()
+    MetricsBuckets = []float64{0.01, 0.1}
+    metrics = &metricsRegistry{series: make(map[metricsKey]*metricsSeries)}
+
+    //same database and index of different tables, told apart by pool
+    db1, _ := sql.Open("mysql", "user:pass@tcp(127.0.0.1:3306)/mydb")
+    db2, _ := sql.Open("mysql", "user:pass@tcp(127.0.0.2:3306)/mydb")
+    db2.SetMaxOpenConns(10)
+    metrics.record(QueryInfo{Kind: QueryKindSelect, Table: "user"}, nil, 5*time.Millisecond, "mydb", []*sql.DB{db1})
+    metrics.record(QueryInfo{Kind: QueryKindSelect, Table: "user"}, errors.New("timeout"), 50*time.Millisecond, "mydb", []*sql.DB{db1})
+    metrics.record(QueryInfo{Kind: QueryKindInsert, Table: "orders"}, nil, 200*time.Millisecond, "mydb", []*sql.DB{db2})
+
+    var str strings.Builder
+    if err := WriteMetrics(&str); err != nil {
+        t.Fatal(err)
+    }
+
+    want := []string{
+        `# TYPE orm_queries_total counter`,
+        `orm_queries_total{table="orders",operation="insert"} 1`,
+        `orm_queries_total{table="user",operation="select"} 2`,
+        `orm_query_errors_total{table="orders",operation="insert"} 0`,
+        `orm_query_errors_total{table="user",operation="select"} 1`,
+        `# TYPE orm_query_duration_seconds histogram`,
+        `orm_query_duration_seconds_bucket{table="user",operation="select",le="0.01"} 1`,
+        `orm_query_duration_seconds_bucket{table="user",operation="select",le="0.1"} 2`,
+        `orm_query_duration_seconds_bucket{table="user",operation="select",le="+Inf"} 2`,
+        `orm_query_duration_seconds_sum{table="user",operation="select"} 0.055`,
+        `orm_query_duration_seconds_count{table="user",operation="select"} 2`,
+        `orm_query_duration_seconds_bucket{table="orders",operation="insert",le="0.1"} 0`,
+        `orm_query_duration_seconds_bucket{table="orders",operation="insert",le="+Inf"} 1`,
+        `# TYPE orm_db_open_connections gauge`,
+        `orm_db_max_open_connections{database="mydb",index="0",pool="1"} 0`,
+        `orm_db_max_open_connections{database="mydb",index="0",pool="2"} 10`,
+        `# TYPE orm_db_wait_count_total counter`,
+    }
+    got := str.String()
+    for _, v := range want {
+        if strings.Contains(got, v+"\n") == false {
+            t.Errorf("missing line %s", v)
+        }
+    }
+
+    //each series once
+    seen := make(map[string]bool)
+    for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+        if strings.HasPrefix(line, "#") {
+            continue
+        }
+        series := line[:strings.LastIndex(line, " ")]
+        if seen[series] {
+            t.Errorf("duplicate series %s", series)
+        }
+        seen[series] = true
+    }
+    if t.Failed() {
+        t.Log(got)
+    }
+}
+
+func TestMetricsLabels(t *testing.T) {
+    tests := []struct {
+        pairs []string
+        want  string
+    }{
+        {pairs: nil, want: ``},
+        {pairs: []string{"table", "user"}, want: `table="user"`},
+        {pairs: []string{"table", "user", "operation", "select"}, want: `table="user",operation="select"`},
+        {pairs: []string{"table", "a\"b\\c\nd"}, want: `table="a\"b\\c\nd"`},
+        {pairs: []string{"table"}, want: ``},
+    }
+
+    for _, tt := range tests {
+        if got := metricsLabels(tt.pairs...); got != tt.want {
+            t.Errorf("%q: want %s, got %s", tt.pairs, tt.want, got)
+        }
+    }
+}
+
+func TestRecordMetrics(t *testing.T) {
+    registry := metrics
+    defer func() {
+        metrics = registry
+        EnableMetrics(false)
+    }()
+    metrics = &metricsRegistry{series: make(map[metricsKey]*metricsSeries)}
+
+    db, fake := newFakeDB()
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        return nil, errors.New("deadlock")
+    }
+    table := new(testRow)
+
+    NewQuery(table, db).Gets()
+    EnableMetrics(true)
+    NewQuery(table, db).Gets()
+    NewQuery(table, db).Gets()
+    NewQuery(table, db).Delete(1)
+
+    keys, series, dbs := metrics.snapshot()
+    want := []metricsKey{{table: "mydb.test_row", operation: QueryKindDelete}, {table: "mydb.test_row", operation: QueryKindSelect}}
+    if reflect.DeepEqual(keys, want) == false {
+        t.Fatalf("want %v, got %v", want, keys)
+    }
+    if series[0].count != 1 || series[0].errors != 1 {
+        t.Errorf("want 1 failed delete, got %+v", series[0])
+    }
+    if series[1].count != 2 || series[1].errors != 0 {
+        t.Errorf("want 2 selects recorded after enabled, got %+v", series[1])
+    }
+    if len(dbs) != 1 || dbs[0].db != db || dbs[0].database != "mydb" {
+        t.Errorf("want db of table, got %+v", dbs)
+    }
+}
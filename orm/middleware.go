@@ -0,0 +1,113 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "time"
+)
+
+type QueryKind string
+
+type DBRole string
+
+const (
+    DBRolePrimary DBRole = "primary"
+    DBRoleReplica DBRole = "replica"
+)
+
+const (
+    QueryKindSelect  QueryKind = "select"
+    QueryKindInsert  QueryKind = "insert"
+    QueryKindUpdate  QueryKind = "update"
+    QueryKindDelete  QueryKind = "delete"
+    QueryKindExecute QueryKind = "execute" //raw sql by Execute
+)
+
+//statement to run, seen by middlewares
+type QueryInfo struct {
+    Kind     QueryKind
+    Table    string //table name with database name
+    Sql      string //prepare sql
+    Bindings []any
+    InTx     bool
+    Role     DBRole
+    Dest     any //dest ptr of GetTo, nil for others
+
+    db *sql.DB
+}
+
+type QueryHandler func(ctx context.Context, info QueryInfo) (QueryResult, error)
+
+//run around every statement, may modify info, call next, or return without calling next
+type Middleware func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error)
+
+//table with its own middlewares, run after global middlewares
+type MiddlewareTable interface {
+    Middlewares() []Middleware
+}
+
+var middlewares []Middleware
+
+//global middlewares, first added runs outermost
+func UseMiddleware(m ...Middleware) {
+    middlewares = append(middlewares, m...)
+}
+
+func (q *Query[T]) runMiddlewares(ctx context.Context, info QueryInfo, handler QueryHandler) (QueryResult, error) {
+    chain := middlewares
+    if len(q.tables) > 0 {
+        if t, ok := q.tables[0].table.(MiddlewareTable); ok {
+            chain = append(append([]Middleware{}, chain...), t.Middlewares()...)
+        }
+    }
+
+    for i := len(chain) - 1; i >= 0; i-- {
+        m, next := chain[i], handler
+        handler = func(ctx context.Context, info QueryInfo) (QueryResult, error) {
+            return m(ctx, info, next)
+        }
+    }
+
+    //attributes built only if traced
+    var span Span
+    if tracer != nil {
+        ctx, span = startSpan(ctx, string(info.Kind)+" "+info.Table, statementSpanAttributes(info)...)
+    }
+    start := time.Now()
+    res, err := handler(ctx, info)
+    duration := time.Since(start)
+    endSpan(span, err, Attribute{Key: "db.rows_affected", Value: res.RowsAffected})
+    logStatement(ctx, info, res, err, duration)
+    q.checkSlowQuery(ctx, info, res, err, duration)
+    q.recordMetrics(info, err, duration)
+    recordQueryStats(info, res, err, duration)
+    detectNPlusOne(ctx, info)
+    return res, err
+}
+
+//db: db to run statement on, nil in transaction
+func (q *Query[T]) queryInfo(kind QueryKind, dest any, db *sql.DB) QueryInfo {
+    info := QueryInfo{
+        Kind:     kind,
+        Sql:      q.result.PrepareSql,
+        Bindings: q.result.Bindings,
+        InTx:     q.tx != nil,
+        Role:     DBRolePrimary,
+        Dest:     dest,
+        db:       db,
+    }
+    if len(q.tables) > 0 {
+        info.Table = q.tables[0].getTableName()
+    }
+    if db != nil && db != q.writeDB() {
+        info.Role = DBRoleReplica
+    }
+    return info
+}
+
+func (q *Query[T]) context() context.Context {
+    if q.ctx != nil {
+        return *q.ctx
+    }
+    return context.Background()
+}
@@ -0,0 +1,226 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+var testTableMiddlewares []Middleware
+
+//table with its own middlewares
+type testMiddlewareRow struct {
+    Id   int    `json:"id"`
+    Name string `json:"name"`
+}
+
+func (*testMiddlewareRow) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testMiddlewareRow) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testMiddlewareRow) TableName() string {
+    return "test_middleware_row"
+}
+
+func (*testMiddlewareRow) Middlewares() []Middleware {
+    return testTableMiddlewares
+}
+
+//global and table middlewares during test
+func withMiddlewares(t *testing.T, global []Middleware, table []Middleware) {
+    old := middlewares
+    middlewares = global
+    testTableMiddlewares = table
+    t.Cleanup(func() {
+        middlewares = old
+        testTableMiddlewares = nil
+    })
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+    withMiddlewares(t, []Middleware{
+        func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error) {
+            if info.Kind == QueryKindUpdate {
+                return QueryResult{PrepareSql: info.Sql, Bindings: info.Bindings, RowsAffected: 7}, nil
+            }
+            if info.Kind == QueryKindDelete {
+                return QueryResult{}, errors.New("delete not allowed")
+            }
+            return next(ctx, info)
+        },
+    }, nil)
+
+    db, fake := newFakeDB()
+    table := new(testRow)
+
+    res := NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if res.RowsAffected != 7 {
+        t.Errorf("want rows affected from middleware, got %d", res.RowsAffected)
+    }
+
+    res = NewQuery(table, db).Delete(1)
+    if res.Err == nil || res.Err.Error() != "delete not allowed" {
+        t.Errorf("want error from middleware, got %v", res.Err)
+    }
+
+    if len(fake.recorded()) != 0 {
+        t.Errorf("want no statement sent to db, got %v", fake.recorded())
+    }
+
+    _, res = NewQuery(table, db).Gets(1)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if len(fake.recorded()) != 1 {
+        t.Errorf("want select passed to db, got %v", fake.recorded())
+    }
+}
+
+func TestMiddlewareRewrite(t *testing.T) {
+    withMiddlewares(t, []Middleware{
+        func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error) {
+            info.Sql = "/* app */ " + info.Sql
+            info.Bindings = append(info.Bindings, "tenant")
+            return next(ctx, info)
+        },
+    }, nil)
+
+    db, fake := newFakeDB()
+    table := new(testRow)
+
+    res := NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+
+    statements := fake.recorded()
+    if len(statements) != 1 {
+        t.Fatalf("want 1 statement, got %d", len(statements))
+    }
+    if strings.HasPrefix(statements[0].sql, "/* app */ update mydb.test_row") == false {
+        t.Errorf("want rewritten sql, got %s", statements[0].sql)
+    }
+    if reflect.DeepEqual(statements[0].args, []any{"john", 1, "tenant"}) == false {
+        t.Errorf("want rewritten bindings, got %v", statements[0].args)
+    }
+    if res.PrepareSql != statements[0].sql {
+        t.Errorf("want result of rewritten sql, got %s", res.PrepareSql)
+    }
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+    var calls []string
+    record := func(name string) Middleware {
+        return func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error) {
+            calls = append(calls, name+" "+string(info.Kind)+" "+info.Table)
+            res, err := next(ctx, info)
+            calls = append(calls, name+" done")
+            return res, err
+        }
+    }
+    withMiddlewares(t, []Middleware{record("global1"), record("global2")}, []Middleware{record("table")})
+
+    db, _ := newFakeDB()
+
+    t.Run("table middlewares after global", func(t *testing.T) {
+        calls = nil
+        table := new(testMiddlewareRow)
+        if res := NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john"); res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        want := []string{
+            "global1 update mydb.test_middleware_row",
+            "global2 update mydb.test_middleware_row",
+            "table update mydb.test_middleware_row",
+            "table done",
+            "global2 done",
+            "global1 done",
+        }
+        if reflect.DeepEqual(calls, want) == false {
+            t.Errorf("\nwant %v\ngot  %v", want, calls)
+        }
+    })
+
+    t.Run("table without middlewares", func(t *testing.T) {
+        calls = nil
+        var rows []testRow
+        if res := NewQuery(new(testRow), db).GetTo(&rows); res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        want := []string{
+            "global1 select mydb.test_row",
+            "global2 select mydb.test_row",
+            "global2 done",
+            "global1 done",
+        }
+        if reflect.DeepEqual(calls, want) == false {
+            t.Errorf("\nwant %v\ngot  %v", want, calls)
+        }
+    })
+}
+
+func TestMiddlewareQueryInfo(t *testing.T) {
+    var infos []QueryInfo
+    withMiddlewares(t, []Middleware{
+        func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error) {
+            infos = append(infos, info)
+            return next(ctx, info)
+        },
+    }, nil)
+
+    db, _ := newFakeDB()
+    table := new(testRow)
+
+    var rows []testRow
+    NewQuery(table, db).GetTo(&rows)
+    NewQuery(table, db).Insert(&testRow{Name: "john"})
+    NewQuery(table, db).Raw("truncate table mydb.test_row").Execute()
+    NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        return query.Delete(1).Err
+    })
+
+    if len(infos) != 4 {
+        t.Fatalf("want 4 statements, got %d", len(infos))
+    }
+    if infos[0].Kind != QueryKindSelect || infos[0].Dest != &rows {
+        t.Errorf("want select with dest, got %+v", infos[0])
+    }
+    if infos[1].Kind != QueryKindInsert || infos[1].Dest != nil {
+        t.Errorf("want insert, got %+v", infos[1])
+    }
+    if infos[2].Kind != QueryKindExecute || infos[2].Sql != "truncate table mydb.test_row" {
+        t.Errorf("want raw execute, got %+v", infos[2])
+    }
+    if infos[3].Kind != QueryKindDelete || infos[3].InTx == false || infos[3].Table != "mydb.test_row" {
+        t.Errorf("want delete in tx, got %+v", infos[3])
+    }
+}
+
+func TestMiddlewareKindReset(t *testing.T) {
+    var kinds []QueryKind
+    withMiddlewares(t, []Middleware{
+        func(ctx context.Context, info QueryInfo, next QueryHandler) (QueryResult, error) {
+            kinds = append(kinds, info.Kind)
+            return next(ctx, info)
+        },
+    }, nil)
+
+    db, _ := newFakeDB()
+    query := NewQuery(new(testRow), db)
+    query.Insert(&testRow{Name: "john"})
+    query.Raw("truncate table mydb.test_row").Execute()
+
+    if want := []QueryKind{QueryKindInsert, QueryKindExecute}; reflect.DeepEqual(kinds, want) == false {
+        t.Errorf("want kind reset after insert %v, got %v", want, kinds)
+    }
+}
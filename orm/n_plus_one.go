@@ -0,0 +1,115 @@
+package orm
+
+import (
+    "context"
+    "sort"
+    "strings"
+    "sync"
+)
+
+//same query shape on same table executed too many times in one context
+type NPlusOneReport struct {
+    Fingerprint string
+    Table       string
+    Count       int
+    Callers     []string //file:line of calls
+    Suggestion  string
+}
+
+type nPlusOneKey struct{}
+
+type nPlusOneDetector struct {
+    threshold int
+    report    func(NPlusOneReport)
+    mu        sync.Mutex
+    counts    map[string]int
+    callers   map[string][]string
+    reports   []NPlusOneReport
+}
+
+//dev mode: track statements run with returned ctx (by WithContext),
+//report when same fingerprint on same table runs more than threshold times, like Get in a loop
+//report once per fingerprint, logged at warn level if report is nil
+func WithNPlusOneDetection(ctx context.Context, threshold int, report func(NPlusOneReport)) context.Context {
+    return context.WithValue(ctx, nPlusOneKey{}, &nPlusOneDetector{
+        threshold: threshold,
+        report:    report,
+        counts:    make(map[string]int),
+        callers:   make(map[string][]string),
+    })
+}
+
+//reports of ctx returned by WithNPlusOneDetection
+func NPlusOneReports(ctx context.Context) []NPlusOneReport {
+    d, ok := ctx.Value(nPlusOneKey{}).(*nPlusOneDetector)
+    if ok == false {
+        return nil
+    }
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return append([]NPlusOneReport{}, d.reports...)
+}
+
+func detectNPlusOne(ctx context.Context, info QueryInfo) {
+    d, ok := ctx.Value(nPlusOneKey{}).(*nPlusOneDetector)
+    if ok == false {
+        return
+    }
+    fingerprint := Fingerprint(info.Sql)
+    key := info.Table + "|" + fingerprint
+    caller := callerOutsideOrm()
+
+    d.mu.Lock()
+    d.counts[key]++
+    count := d.counts[key]
+    if count > d.threshold+1 {
+        d.mu.Unlock()
+        return
+    }
+    if sliceContainIndex(d.callers[key], caller) < 0 {
+        d.callers[key] = append(d.callers[key], caller)
+    }
+    if count <= d.threshold {
+        d.mu.Unlock()
+        return
+    }
+    callers := append([]string{}, d.callers[key]...)
+    sort.Strings(callers)
+    report := NPlusOneReport{
+        Fingerprint: fingerprint,
+        Table:       info.Table,
+        Count:       count,
+        Callers:     callers,
+        Suggestion:  nPlusOneSuggestion(info.Kind),
+    }
+    d.reports = append(d.reports, report)
+    d.mu.Unlock()
+
+    if d.report != nil {
+        d.report(report)
+    } else if structuredLogger != nil {
+        structuredLogger.WarnContext(ctx, "orm n+1 query",
+            "fingerprint", report.Fingerprint,
+            "table", report.Table,
+            "count", report.Count,
+            "callers", strings.Join(report.Callers, ","),
+            "suggestion", report.Suggestion,
+        )
+    } else if errorLogger != nil {
+        errorLogger.Error("n+1 query", report.Table, report.Fingerprint, report.Callers, report.Suggestion)
+    }
+}
+
+func nPlusOneSuggestion(kind QueryKind) string {
+    switch kind {
+    case QueryKindSelect:
+        return "get rows at once by WherePrimary with a slice of ids, or join the table"
+    case QueryKindInsert:
+        return "insert rows at once by Insert with multiple rows"
+    case QueryKindUpdate:
+        return "update rows at once by BulkUpdate, or Update with WherePrimary of a slice of ids"
+    case QueryKindDelete:
+        return "delete rows at once by WherePrimary with a slice of ids"
+    }
+    return "run statements in batch instead of one by one"
+}
@@ -0,0 +1,68 @@
+package orm
+
+import (
+    "context"
+    "testing"
+)
+
+func TestNPlusOneDetection(t *testing.T) {
+    var reported []NPlusOneReport
+    ctx := WithNPlusOneDetection(context.Background(), 3, func(report NPlusOneReport) {
+        reported = append(reported, report)
+    })
+
+    db, _ := newFakeDB()
+    table := new(testRow)
+
+    for id := 1; id <= 3; id++ {
+        NewQuery(table, db).WithContext(ctx).Gets(id)
+    }
+    if len(reported) != 0 {
+        t.Fatalf("want no report within threshold, got %+v", reported)
+    }
+
+    for id := 4; id <= 6; id++ {
+        NewQuery(table, db).WithContext(ctx).Gets(id)
+    }
+    //other shapes counted apart
+    NewQuery(table, db).WithContext(ctx).Where(&table.Name, "john").Update(&table.Data, "x")
+    NewQuery(table, db).Gets(7)
+
+    if len(reported) != 1 {
+        t.Fatalf("want reported once, got %+v", reported)
+    }
+    report := reported[0]
+    if report.Count != 4 || report.Table != "mydb.test_row" || report.Fingerprint != "select * from mydb.test_row where mydb.test_row.`id` = ?" {
+        t.Errorf("want select reported at 4th run, got %+v", report)
+    }
+    if report.Suggestion != nPlusOneSuggestion(QueryKindSelect) || len(report.Callers) != 1 {
+        t.Errorf("want suggestion and caller, got %+v", report)
+    }
+
+    reports := NPlusOneReports(ctx)
+    if len(reports) != 1 || reports[0].Fingerprint != report.Fingerprint {
+        t.Errorf("want reports kept in ctx, got %+v", reports)
+    }
+    if NPlusOneReports(context.Background()) != nil {
+        t.Error("want no reports without detection")
+    }
+}
+
+func TestNPlusOneLogged(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelOff, Error: LogLevelOff, Slow: LogLevelOff})
+    ctx := WithNPlusOneDetection(context.Background(), 1, nil)
+
+    db, _ := newFakeDB()
+    table := new(testRow)
+    for id := 1; id <= 3; id++ {
+        NewQuery(table, db).WithContext(ctx).Where(&table.Id, id).Update(&table.Name, "john")
+    }
+
+    entries := l.logged()
+    if len(entries) != 1 || entries[0].level != LogLevelWarn || entries[0].msg != "orm n+1 query" {
+        t.Fatalf("want one warn entry, got %+v", entries)
+    }
+    if entries[0].fields["count"] != 2 || entries[0].fields["suggestion"] != nPlusOneSuggestion(QueryKindUpdate) {
+        t.Errorf("want update reported at 2nd run, got %v", entries[0].fields)
+    }
+}
@@ -0,0 +1,147 @@
+package ormtest
+
+import (
+    "context"
+    "database/sql/driver"
+    "errors"
+    "io"
+)
+
+type mockDriver struct {
+    mock *Mock
+}
+
+func (d *mockDriver) Open(name string) (driver.Conn, error) {
+    return &mockConn{mock: d.mock}, nil
+}
+
+type mockConn struct {
+    mock *Mock
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+    return nil, errors.New("ormtest: prepared statement not supported")
+}
+
+func (c *mockConn) Close() error {
+    return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+    return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *mockConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+    e, err := c.mock.match(expectBegin, "", nil)
+    if err != nil {
+        return nil, err
+    }
+    if e != nil && e.err != nil {
+        return nil, e.err
+    }
+    return &mockTx{mock: c.mock}, nil
+}
+
+//accept any binding, converted like database/sql if possible
+func (c *mockConn) CheckNamedValue(v *driver.NamedValue) error {
+    v.Value = driverValue(v.Value)
+    return nil
+}
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    if v, ok := c.mock.serverVariable(query); ok {
+        return &mockRows{columns: []string{query[len("select "):]}, rows: [][]driver.Value{{v}}}, nil
+    }
+    if isSessionStatus(query) {
+        //rows examined of query stats unknown
+        return &mockRows{columns: []string{"Variable_name", "Value"}}, nil
+    }
+    e, err := c.mock.match(expectQuery, query, args)
+    if err != nil {
+        return nil, err
+    }
+    if e == nil {
+        return &mockRows{}, nil
+    }
+    if e.err != nil {
+        return nil, e.err
+    }
+    return &mockRows{columns: e.columns, rows: e.rows}, nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    e, err := c.mock.match(expectExec, query, args)
+    if err != nil {
+        return nil, err
+    }
+    if e == nil {
+        return driver.RowsAffected(0), nil
+    }
+    if e.err != nil {
+        return nil, e.err
+    }
+    return mockResult{lastInsertId: e.lastInsertId, rowsAffected: e.rowsAffected}, nil
+}
+
+func (c *mockConn) Ping(ctx context.Context) error {
+    return nil
+}
+
+type mockTx struct {
+    mock *Mock
+}
+
+func (t *mockTx) Commit() error {
+    return t.end(expectCommit)
+}
+
+func (t *mockTx) Rollback() error {
+    return t.end(expectRollback)
+}
+
+func (t *mockTx) end(kind expectKind) error {
+    e, err := t.mock.match(kind, "", nil)
+    if err != nil {
+        return err
+    }
+    if e != nil {
+        return e.err
+    }
+    return nil
+}
+
+type mockResult struct {
+    lastInsertId int64
+    rowsAffected int64
+}
+
+func (r mockResult) LastInsertId() (int64, error) {
+    return r.lastInsertId, nil
+}
+
+func (r mockResult) RowsAffected() (int64, error) {
+    return r.rowsAffected, nil
+}
+
+type mockRows struct {
+    columns []string
+    rows    [][]driver.Value
+    index   int
+}
+
+func (r *mockRows) Columns() []string {
+    return r.columns
+}
+
+func (r *mockRows) Close() error {
+    return nil
+}
+
+func (r *mockRows) Next(dest []driver.Value) error {
+    if r.index >= len(r.rows) {
+        return io.EOF
+    }
+    copy(dest, r.rows[r.index])
+    r.index++
+    return nil
+}
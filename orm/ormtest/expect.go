@@ -0,0 +1,216 @@
+package ormtest
+
+import (
+    "database/sql/driver"
+    "fmt"
+    "github.com/folospace/go-mysql-orm/orm"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+type expectKind string
+
+const (
+    expectQuery    expectKind = "query"
+    expectExec     expectKind = "exec"
+    expectBegin    expectKind = "begin"
+    expectCommit   expectKind = "commit"
+    expectRollback expectKind = "rollback"
+)
+
+//match sql of statement
+type Matcher interface {
+    Match(sql string) bool
+    Diff(sql string) string //why sql not matched
+    String() string
+}
+
+//same sql, leading and trailing spaces ignored
+func Exact(sql string) Matcher {
+    return exactMatcher(strings.TrimSpace(sql))
+}
+
+//sql matched by regular expression
+func Regexp(pattern string) Matcher {
+    return regexpMatcher{re: regexp.MustCompile(pattern)}
+}
+
+//same fingerprint by orm.Fingerprint, literals and in lists ignored
+func Fingerprint(sql string) Matcher {
+    return fingerprintMatcher(orm.Fingerprint(sql))
+}
+
+type exactMatcher string
+
+func (m exactMatcher) Match(sql string) bool {
+    return strings.TrimSpace(sql) == string(m)
+}
+
+func (m exactMatcher) Diff(sql string) string {
+    return diffString(string(m), strings.TrimSpace(sql))
+}
+
+func (m exactMatcher) String() string {
+    return "exact " + strconv.Quote(string(m))
+}
+
+type regexpMatcher struct {
+    re *regexp.Regexp
+}
+
+func (m regexpMatcher) Match(sql string) bool {
+    return m.re.MatchString(sql)
+}
+
+func (m regexpMatcher) Diff(sql string) string {
+    return "  want match: " + m.re.String() + "\n  got:        " + sql
+}
+
+func (m regexpMatcher) String() string {
+    return "regexp " + strconv.Quote(m.re.String())
+}
+
+type fingerprintMatcher string
+
+func (m fingerprintMatcher) Match(sql string) bool {
+    return orm.Fingerprint(sql) == string(m)
+}
+
+func (m fingerprintMatcher) Diff(sql string) string {
+    return diffString(string(m), orm.Fingerprint(sql))
+}
+
+func (m fingerprintMatcher) String() string {
+    return "fingerprint " + strconv.Quote(string(m))
+}
+
+//want and got with ^ under first different byte
+func diffString(want, got string) string {
+    index := 0
+    for index < len(want) && index < len(got) && want[index] == got[index] {
+        index++
+    }
+    return "  want: " + want + "\n  got:  " + got + "\n        " + strings.Repeat(" ", index) + "^ differs at byte " + strconv.Itoa(index)
+}
+
+type anyArg struct{}
+
+//match any binding in WithArgs
+var AnyArg = anyArg{}
+
+//expected statement and its answer
+type Expectation struct {
+    kind         expectKind
+    match        Matcher
+    args         []any
+    argsSet      bool
+    columns      []string
+    rows         [][]driver.Value
+    lastInsertId int64
+    rowsAffected int64
+    err          error
+    times        int
+    matched      int
+}
+
+//bindings expected, AnyArg for any value
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+    e.args = args
+    e.argsSet = true
+    return e
+}
+
+//rows returned by query
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]any) *Expectation {
+    e.columns = columns
+    e.rows = make([][]driver.Value, len(rows))
+    for k, row := range rows {
+        e.rows[k] = make([]driver.Value, len(row))
+        for i, v := range row {
+            e.rows[k][i] = driverValue(v)
+        }
+    }
+    return e
+}
+
+//result of exec
+func (e *Expectation) WillReturnResult(lastInsertId, rowsAffected int64) *Expectation {
+    e.lastInsertId = lastInsertId
+    e.rowsAffected = rowsAffected
+    return e
+}
+
+func (e *Expectation) WillReturnError(err error) *Expectation {
+    e.err = err
+    return e
+}
+
+//matched n times, 1 by default
+func (e *Expectation) Times(n int) *Expectation {
+    e.times = n
+    return e
+}
+
+func (e *Expectation) String() string {
+    str := string(e.kind)
+    if e.match != nil {
+        str += " " + e.match.String()
+    }
+    if e.argsSet {
+        str += fmt.Sprintf(" with args %v", e.args)
+    }
+    return str
+}
+
+func (e *Expectation) isTx() bool {
+    return e.kind == expectBegin || e.kind == expectCommit || e.kind == expectRollback
+}
+
+func (e *Expectation) argsMatch(values []any) bool {
+    if e.argsSet == false {
+        return true
+    }
+    if len(e.args) != len(values) {
+        return false
+    }
+    for k, v := range e.args {
+        if argMatch(v, values[k]) == false {
+            return false
+        }
+    }
+    return true
+}
+
+func (e *Expectation) argsDiff(values []any) string {
+    var strs []string
+    if len(e.args) != len(values) {
+        strs = append(strs, fmt.Sprintf("  want %d args, got %d", len(e.args), len(values)))
+    }
+    for k := 0; k < len(e.args) && k < len(values); k++ {
+        if argMatch(e.args[k], values[k]) == false {
+            strs = append(strs, fmt.Sprintf("  arg %d: want %#v, got %#v", k, driverValue(e.args[k]), values[k]))
+        }
+    }
+    return strings.Join(strs, "\n")
+}
+
+func argMatch(want, got any) bool {
+    if _, ok := want.(anyArg); ok {
+        return true
+    }
+    return reflect.DeepEqual(driverValue(want), got)
+}
+
+//value converted like database/sql, int to int64, Valuer to its value
+func driverValue(v any) driver.Value {
+    if _, ok := v.(anyArg); ok {
+        return v
+    }
+    ret, err := driver.DefaultParameterConverter.ConvertValue(v)
+    if err != nil {
+        return v
+    }
+    return ret
+}
@@ -0,0 +1,225 @@
+package ormtest
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "fmt"
+    "github.com/folospace/go-mysql-orm/orm"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+var driverId int64
+
+//testing.T, testing.B
+type TB interface {
+    Helper()
+    Errorf(format string, args ...any)
+}
+
+//statement received by mock db
+type Statement struct {
+    Sql   string
+    Args  []any
+    Query bool //query or exec
+}
+
+func (s Statement) String() string {
+    return orm.QueryResult{PrepareSql: s.Sql, Bindings: s.Args}.Sql()
+}
+
+//mock of mysql db, records statements, answers by expectations
+type Mock struct {
+    t               TB
+    db              *sql.DB
+    mu              sync.Mutex
+    expectations    []*Expectation
+    statements      []Statement
+    anyOrder        bool
+    allowUnexpected bool
+    variables       map[string]string
+}
+
+//new mock with its own driver registered by orm.Register, t is optional for reporting mismatches
+func New(t TB) *Mock {
+    m := &Mock{
+        t: t,
+        variables: map[string]string{
+            "version":                  "8.0.36",
+            "auto_increment_increment": "1",
+            "max_allowed_packet":       "67108864",
+        },
+    }
+    name := "ormtest_" + strconv.FormatInt(atomic.AddInt64(&driverId, 1), 10)
+    orm.Register(name, &mockDriver{mock: m})
+    m.db, _ = orm.Open(name, name)
+    return m
+}
+
+//db to use in Connections() of tables, or Query.UseDB
+func (m *Mock) DB() *sql.DB {
+    return m.db
+}
+
+//value of "select @@name", version 8.0.36, auto_increment_increment 1, max_allowed_packet 64M by default
+func (m *Mock) SetServerVariable(name, value string) *Mock {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.variables[name] = value
+    return m
+}
+
+//match expectations in any order, in order of adding by default
+func (m *Mock) MatchInAnyOrder() *Mock {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.anyOrder = true
+    return m
+}
+
+//statement not expected answered with empty rows or result, instead of error
+func (m *Mock) AllowUnexpected() *Mock {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.allowUnexpected = true
+    return m
+}
+
+//expect query returning rows, like select
+func (m *Mock) ExpectQuery(match Matcher) *Expectation {
+    return m.expect(expectQuery, match)
+}
+
+//expect exec returning result, like insert, update, delete
+func (m *Mock) ExpectExec(match Matcher) *Expectation {
+    return m.expect(expectExec, match)
+}
+
+func (m *Mock) ExpectBegin() *Expectation {
+    return m.expect(expectBegin, nil)
+}
+
+func (m *Mock) ExpectCommit() *Expectation {
+    return m.expect(expectCommit, nil)
+}
+
+func (m *Mock) ExpectRollback() *Expectation {
+    return m.expect(expectRollback, nil)
+}
+
+func (m *Mock) expect(kind expectKind, match Matcher) *Expectation {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    e := &Expectation{kind: kind, match: match, times: 1}
+    m.expectations = append(m.expectations, e)
+    return e
+}
+
+//statements received, in order, server variable and session status queries excluded
+func (m *Mock) Statements() []Statement {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return append([]Statement{}, m.statements...)
+}
+
+//error listing expectations not met
+func (m *Mock) ExpectationsWereMet() error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    var strs []string
+    for _, v := range m.expectations {
+        if v.matched < v.times {
+            strs = append(strs, "  "+v.String()+" (matched "+strconv.Itoa(v.matched)+" of "+strconv.Itoa(v.times)+")")
+        }
+    }
+    if len(strs) > 0 {
+        return fmt.Errorf("ormtest: expectations not met:\n%s", strings.Join(strs, "\n"))
+    }
+    return nil
+}
+
+//expectation matched by statement, or error with diff
+func (m *Mock) match(kind expectKind, sqlStr string, args []driver.NamedValue) (*Expectation, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    values := make([]any, len(args))
+    for k, v := range args {
+        values[k] = v.Value
+    }
+    if kind == expectQuery || kind == expectExec {
+        m.statements = append(m.statements, Statement{Sql: sqlStr, Args: values, Query: kind == expectQuery})
+    }
+
+    var closest *Expectation
+    for _, e := range m.expectations {
+        if e.matched >= e.times {
+            continue
+        }
+        if e.kind == kind && (e.match == nil || e.match.Match(sqlStr)) && e.argsMatch(values) {
+            e.matched++
+            return e, nil
+        }
+        if closest == nil {
+            closest = e
+        }
+        if m.anyOrder == false {
+            break
+        }
+    }
+
+    isTx := kind == expectBegin || kind == expectCommit || kind == expectRollback
+    if isTx && (closest == nil || closest.isTx() == false) {
+        //transaction not expected, allowed
+        return nil, nil
+    }
+    if m.allowUnexpected && isTx == false {
+        return nil, nil
+    }
+
+    err := m.mismatchError(kind, sqlStr, values, closest)
+    if m.t != nil {
+        m.t.Helper()
+        m.t.Errorf("%s", err.Error())
+    }
+    return nil, err
+}
+
+func (m *Mock) mismatchError(kind expectKind, sqlStr string, values []any, closest *Expectation) error {
+    var str strings.Builder
+    str.WriteString("ormtest: unexpected " + string(kind))
+    if kind == expectQuery || kind == expectExec {
+        str.WriteString(": " + Statement{Sql: sqlStr, Args: values}.String())
+    }
+    if closest == nil {
+        str.WriteString("\n  no expectation left")
+        return fmt.Errorf("%s", str.String())
+    }
+    str.WriteString("\n  next expectation: " + closest.String())
+    if closest.kind != kind {
+        str.WriteString("\n  want " + string(closest.kind) + ", got " + string(kind))
+    } else if closest.match != nil && closest.match.Match(sqlStr) == false {
+        str.WriteString("\n" + closest.match.Diff(sqlStr))
+    } else {
+        str.WriteString("\n" + closest.argsDiff(values))
+    }
+    return fmt.Errorf("%s", str.String())
+}
+
+func (m *Mock) serverVariable(sqlStr string) (string, bool) {
+    lower := strings.ToLower(strings.TrimSpace(sqlStr))
+    if strings.HasPrefix(lower, "select @@") == false {
+        return "", false
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    v, ok := m.variables[strings.TrimPrefix(lower, "select @@")]
+    return v, ok
+}
+
+//show session status of query stats
+func isSessionStatus(sqlStr string) bool {
+    return strings.HasPrefix(strings.ToLower(strings.TrimSpace(sqlStr)), "show session status")
+}
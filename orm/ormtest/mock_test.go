@@ -0,0 +1,268 @@
+package ormtest_test
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "github.com/folospace/go-mysql-orm/orm"
+    "github.com/folospace/go-mysql-orm/orm/ormtest"
+    "strings"
+    "testing"
+)
+
+type User struct {
+    Id   int    `json:"id"`
+    Name string `json:"name"`
+}
+
+func (*User) Connections() []*sql.DB {
+    return nil
+}
+
+func (*User) DatabaseName() string {
+    return "mydb"
+}
+
+func (*User) TableName() string {
+    return "user"
+}
+
+var UserTable = new(User)
+
+//errors reported by mock, instead of failing test
+type recorder struct {
+    errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+    r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectQuery(t *testing.T) {
+    mock := ormtest.New(t)
+    mock.ExpectQuery(ormtest.Exact("select * from mydb.user where mydb.user.`id` = ? limit 1")).
+        WithArgs(1).
+        WillReturnRows([]string{"id", "name"}, []any{1, "john"})
+
+    user, res := orm.NewQuery(UserTable, mock.DB()).Get(1)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if user.Id != 1 || user.Name != "john" {
+        t.Errorf("got %+v", user)
+    }
+
+    statements := mock.Statements()
+    if len(statements) != 1 || statements[0].Query == false || statements[0].String() != "select * from mydb.user where mydb.user.`id` = 1 limit 1" {
+        t.Errorf("statements %v", statements)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestExpectExec(t *testing.T) {
+    mock := ormtest.New(t)
+    mock.ExpectExec(ormtest.Regexp("^insert into mydb.user")).WithArgs(ormtest.AnyArg, "john").WillReturnResult(7, 1)
+    mock.ExpectExec(ormtest.Fingerprint("update mydb.user set mydb.user.`name` = 'a' where mydb.user.`id` in (1)")).
+        WillReturnResult(0, 2)
+    mock.ExpectExec(ormtest.Regexp("^delete")).WillReturnError(errors.New("lock wait timeout"))
+
+    user := &User{Name: "john"}
+    res := orm.NewQuery(UserTable, mock.DB()).Insert(user)
+    if res.Err != nil || user.Id != 7 {
+        t.Errorf("insert id %d, err %v", user.Id, res.Err)
+    }
+
+    res = orm.NewQuery(UserTable, mock.DB()).WherePrimary([]int{1, 2}).Update(&UserTable.Name, "john")
+    if res.Err != nil || res.RowsAffected != 2 {
+        t.Errorf("update rows affected %d, err %v", res.RowsAffected, res.Err)
+    }
+
+    res = orm.NewQuery(UserTable, mock.DB()).Delete(1)
+    if res.Err == nil || res.Err.Error() != "lock wait timeout" {
+        t.Errorf("delete err %v", res.Err)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestMismatch(t *testing.T) {
+    tests := []struct {
+        name   string
+        expect func(mock *ormtest.Mock)
+        want   string
+    }{
+        {
+            name: "sql",
+            expect: func(mock *ormtest.Mock) {
+                mock.ExpectQuery(ormtest.Exact("select * from mydb.user where mydb.user.`id` = ? limit 2"))
+            },
+            want: "^ differs at byte 55",
+        },
+        {
+            name: "args",
+            expect: func(mock *ormtest.Mock) {
+                mock.ExpectQuery(ormtest.Regexp("^select")).WithArgs(2)
+            },
+            want: "arg 0: want 2, got 1",
+        },
+        {
+            name: "kind",
+            expect: func(mock *ormtest.Mock) {
+                mock.ExpectExec(ormtest.Regexp("^select"))
+            },
+            want: "want exec, got query",
+        },
+        {
+            name:   "no expectation",
+            expect: func(mock *ormtest.Mock) {},
+            want:   "no expectation left",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := &recorder{}
+            mock := ormtest.New(r)
+            tt.expect(mock)
+
+            _, res := orm.NewQuery(UserTable, mock.DB()).Get(1)
+            if res.Err == nil {
+                t.Fatal("want error")
+            }
+            if len(r.errors) != 1 || strings.Contains(r.errors[0], tt.want) == false {
+                t.Errorf("want %q in %q", tt.want, r.errors)
+            }
+        })
+    }
+}
+
+func TestExpectationsWereMet(t *testing.T) {
+    mock := ormtest.New(t)
+    mock.ExpectExec(ormtest.Regexp("^update")).Times(2)
+
+    _ = orm.NewQuery(UserTable, mock.DB()).WherePrimary(1).Update(&UserTable.Name, "john")
+
+    err := mock.ExpectationsWereMet()
+    if err == nil || strings.Contains(err.Error(), "(matched 1 of 2)") == false {
+        t.Errorf("got %v", err)
+    }
+}
+
+func TestTransaction(t *testing.T) {
+    tests := []struct {
+        name     string
+        rollback bool
+    }{
+        {name: "commit"},
+        {name: "rollback", rollback: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            mock := ormtest.New(t)
+            mock.ExpectBegin()
+            mock.ExpectExec(ormtest.Regexp("^update")).WillReturnResult(0, 1)
+            if tt.rollback {
+                mock.ExpectRollback()
+            } else {
+                mock.ExpectCommit()
+            }
+
+            err := orm.NewQuery(UserTable, mock.DB()).Transaction(func(query *orm.Query[*User]) error {
+                if res := query.WherePrimary(1).Update(&UserTable.Name, "john"); res.Err != nil {
+                    return res.Err
+                }
+                if tt.rollback {
+                    return errors.New("rollback")
+                }
+                return nil
+            })
+            if (err != nil) != tt.rollback {
+                t.Errorf("got %v", err)
+            }
+            if err := mock.ExpectationsWereMet(); err != nil {
+                t.Error(err)
+            }
+        })
+    }
+}
+
+func TestSetServerVariable(t *testing.T) {
+    tests := []struct {
+        version string
+        want    string
+    }{
+        {version: "8.0.36", want: "as new on duplicate key update mydb.user.`name` = new.`name`"},
+        {version: "5.7.40", want: "on duplicate key update mydb.user.`name` = values(`name`)"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.version, func(t *testing.T) {
+            mock := ormtest.New(t).SetServerVariable("version", tt.version)
+            mock.ExpectExec(ormtest.Regexp("^insert")).WillReturnResult(1, 1)
+
+            res := orm.NewQuery(UserTable, mock.DB()).
+                OnConflictUpdate(&UserTable.Name, &UserTable.Name).
+                Insert(&User{Name: "john"})
+            if res.Err != nil {
+                t.Fatal(res.Err)
+            }
+            //server variable queries not recorded
+            statements := mock.Statements()
+            if len(statements) != 1 || strings.Contains(statements[0].Sql, tt.want) == false {
+                t.Errorf("want %q in %v", tt.want, statements)
+            }
+        })
+    }
+}
+
+func TestMatchInAnyOrder(t *testing.T) {
+    mock := ormtest.New(t).MatchInAnyOrder()
+    mock.ExpectExec(ormtest.Regexp("^delete"))
+    mock.ExpectExec(ormtest.Regexp("^update"))
+
+    _ = orm.NewQuery(UserTable, mock.DB()).WherePrimary(1).Update(&UserTable.Name, "john")
+    _ = orm.NewQuery(UserTable, mock.DB()).Delete(1)
+
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestAllowUnexpected(t *testing.T) {
+    r := &recorder{}
+    mock := ormtest.New(r).AllowUnexpected()
+
+    users, res := orm.NewQuery(UserTable, mock.DB()).Gets()
+    if res.Err != nil || len(users) != 0 || len(r.errors) > 0 {
+        t.Errorf("got %v, err %v, reported %v", users, res.Err, r.errors)
+    }
+    if len(mock.Statements()) != 1 {
+        t.Errorf("statements %v", mock.Statements())
+    }
+}
+
+func TestQueryStatsSessionStatus(t *testing.T) {
+    orm.EnableQueryStats(true)
+    defer func() {
+        orm.EnableQueryStats(false)
+        orm.ResetQueryStats()
+    }()
+
+    mock := ormtest.New(t)
+    mock.ExpectQuery(ormtest.Regexp("^select")).WillReturnRows([]string{"id", "name"}, []any{1, "john"})
+
+    users, res := orm.NewQuery(UserTable, mock.DB()).Gets()
+    if res.Err != nil || len(users) != 1 {
+        t.Fatalf("got %v, err %v", users, res.Err)
+    }
+    //session status queries not recorded
+    if len(mock.Statements()) != 1 {
+        t.Errorf("statements %v", mock.Statements())
+    }
+}
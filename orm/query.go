@@ -4,10 +4,10 @@ import (
     "context"
     "database/sql"
     "github.com/mcuadros/go-defaults"
-    "math/rand"
     "reflect"
     "strconv"
     "strings"
+    "time"
 )
 
 type Raw string
@@ -27,6 +27,10 @@ type Query[T Table] struct {
     T               T
     columns         []any
     insertIgnore    bool
+    replace         bool
+    useRowAlias     *bool
+    rowAlias        string //alias of insert rows in on duplicate key update
+    rowAliasValue   bool   //row alias returned by InsertedValue
     conflictUpdates []updateColumn
     prepareSql      string
     bindings        []any
@@ -37,6 +41,12 @@ type Query[T Table] struct {
     windows         []*SubQuery
     self            *Query[*SubQuery]
     selectTimeout   string
+    batchSize       int
+    kind            QueryKind
+    cacheTTL        time.Duration
+    dryRun          bool //generate sql only, by ToUpdateSql...
+    strictColumns   *bool
+    pendingColumns  []string //not in tables yet, checked again after joins
 }
 
 //query table[struct] generics
@@ -109,13 +119,22 @@ func (q *Query[T]) writeDB() *sql.DB {
 }
 func (q *Query[T]) readDB() *sql.DB {
     dbs := q.DBs()
-    if len(dbs) > 1 {
-        return dbs[rand.Intn(len(dbs)-1)+1] //rand get db
+    if len(dbs) > 1 && mustReadWriteDB(q.ctx) == false {
+        return q.readPolicy().ReadDB(dbs)
     } else {
         return q.writeDB()
     }
 }
 
+func (q *Query[T]) readPolicy() ReadPolicy {
+    if len(q.tables) > 0 {
+        if t, ok := q.tables[0].table.(ReadPolicyTable); ok && t.ReadPolicy() != nil {
+            return t.ReadPolicy()
+        }
+    }
+    return defaultReadPolicy
+}
+
 func (q *Query[T]) tableInterface() Table {
     return any(q.T).(Table)
 }
@@ -132,6 +151,9 @@ func (q *Query[T]) fromTable(table Table) *Query[T] {
 
     if newTable.rawSql != "" {
         newTable.alias = subqueryDefaultName
+    } else if _, ok := table.(ShardTable); ok {
+        //columns prefixed by table name before shard routed
+        newTable.alias = table.TableName()
     }
     q.tables = append(q.tables, newTable)
     return q
@@ -238,6 +260,12 @@ func (q *Query[T]) parseColumn(v any) (string, error) {
             return prefix + ret, nil
         } else if ret == "" {
             return "", ErrColumnShouldBeStringOrPtr
+        } else if _, ok := v.(Raw); ok == false {
+            if q.checkColumnName(ret) != nil {
+                //table of column may be joined later, copied not to share with clones
+                q.pendingColumns = append(append([]string{}, q.pendingColumns...), ret)
+            }
+            return ret, nil
         } else {
             return ret, nil
         }
@@ -289,6 +317,19 @@ func (q *Query[T]) Offset(offset int) *Query[T] {
     return q
 }
 
+//rows per statement of batch operations, like BulkUpdate
+func (q *Query[T]) BatchSize(size int) *Query[T] {
+    q.batchSize = size
+    return q
+}
+
+func (q *Query[T]) getBatchSize() int {
+    if q.batchSize > 0 {
+        return q.batchSize
+    }
+    return defaultBatchSize
+}
+
 //should not use group by after order by
 func (q *Query[T]) GroupBy(columns ...any) *Query[T] {
     q.groupBy = append(q.groupBy, columns...)
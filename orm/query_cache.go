@@ -0,0 +1,321 @@
+package orm
+
+import (
+    "container/list"
+    "database/sql"
+    "fmt"
+    "reflect"
+    "sync"
+    "time"
+)
+
+const defaultQueryCacheCapacity = 10000
+
+//cache of GetTo results, invalidated by tables written through Execute
+type QueryCache interface {
+    Get(key string) (any, bool)
+    Set(key string, value any, ttl time.Duration, tables ...string) //tables read by the query
+    Invalidate(table string)                                        //remove entries of table
+}
+
+var queryCache QueryCache = NewLRUQueryCache(defaultQueryCacheCapacity)
+
+func SetQueryCache(c QueryCache) {
+    queryCache = c
+}
+
+//memoize GetTo result by sql and bindings for ttl, not used in transaction
+//entries invalidated when tables of query written by Execute
+func (q *Query[T]) Cache(ttl time.Duration) *Query[T] {
+    q.cacheTTL = ttl
+    return q
+}
+
+type cachedResult struct {
+    value        reflect.Value
+    rowsAffected int64
+}
+
+func (q *Query[T]) useQueryCache() bool {
+    return q.cacheTTL > 0 && q.tx == nil && queryCache != nil
+}
+
+//write db of query (replicas share it), type of dest and sql with bindings
+func queryCacheKey(db *sql.DB, dest any, query *SubQuery) string {
+    return fmt.Sprintf("%p|", db) + reflect.TypeOf(dest).String() + "|" + QueryResult{PrepareSql: query.raw, Bindings: query.bindings}.Sql()
+}
+
+//set dest from cache, true if hit
+func (q *Query[T]) getCachedResult(key string, dest any) bool {
+    destValue := reflect.ValueOf(dest)
+    if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+        return false
+    }
+    v, ok := queryCache.Get(key)
+    if ok == false {
+        return false
+    }
+    cached, ok := v.(cachedResult)
+    if ok == false || cached.value.Type() != destValue.Elem().Type() {
+        return false
+    }
+    destValue.Elem().Set(deepCopyValue(cached.value))
+    q.result.RowsAffected = cached.rowsAffected
+    return true
+}
+
+func (q *Query[T]) setCachedResult(key string, dest any) {
+    destValue := reflect.ValueOf(dest)
+    if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+        return
+    }
+    queryCache.Set(key, cachedResult{
+        value:        deepCopyValue(destValue.Elem()),
+        rowsAffected: q.result.RowsAffected,
+    }, q.cacheTTL, q.readTables()...)
+}
+
+//tables read by query, including tables of subqueries in from, join, where, having, cte and union
+func (q *Query[T]) readTables() []string {
+    var tables []string
+    seen := make(map[string]bool)
+    add := func(names ...string) {
+        for _, v := range names {
+            if v != "" && seen[v] == false {
+                seen[v] = true
+                tables = append(tables, v)
+            }
+        }
+    }
+    for _, v := range q.tables {
+        if v.rawSql == "" {
+            add(v.getTableName())
+        }
+        switch sub := v.table.(type) {
+        case *SubQuery:
+            add(sub.tables...)
+        case SubQuery:
+            add(sub.tables...)
+        }
+    }
+    for _, subs := range [][]*SubQuery{q.withCtes, q.unions, q.windows} {
+        for _, v := range subs {
+            add(v.tables...)
+        }
+    }
+    add(whereSubQueryTables(q.wheres)...)
+    add(whereSubQueryTables(q.having)...)
+    return tables
+}
+
+func whereSubQueryTables(wheres []where) []string {
+    var tables []string
+    for _, v := range wheres {
+        if sub, ok := v.Val.(*SubQuery); ok {
+            tables = append(tables, sub.tables...)
+        }
+        tables = append(tables, whereSubQueryTables(v.SubWheres)...)
+    }
+    return tables
+}
+
+//cache evictions of statements in transactions of Transaction, run after commit
+var txCacheEvictions sync.Map //*sql.Tx => *txEvictions
+
+type txEvictions struct {
+    mu    sync.Mutex
+    funcs []func()
+}
+
+func (e *txEvictions) add(f func()) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.funcs = append(e.funcs, f)
+}
+
+func beginTxEvictions(tx *sql.Tx) {
+    txCacheEvictions.Store(tx, &txEvictions{})
+}
+
+//run evictions of tx if committed, drop them if rolled back
+func endTxEvictions(tx *sql.Tx, committed bool) {
+    v, ok := txCacheEvictions.LoadAndDelete(tx)
+    if ok == false || committed == false {
+        return
+    }
+    e := v.(*txEvictions)
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    for _, f := range e.funcs {
+        f()
+    }
+}
+
+//evict query cache and entity cache of tables written,
+//deferred to commit in Transaction, tx from elsewhere evicted at once
+func (q *Query[T]) evictCaches(kind QueryKind) {
+    if q.tx != nil {
+        if v, ok := txCacheEvictions.Load(q.tx); ok {
+            nq := q.Clone()
+            v.(*txEvictions).add(func() {
+                nq.invalidateQueryCache()
+                nq.evictEntityCache(kind)
+            })
+            return
+        }
+    }
+    q.invalidateQueryCache()
+    q.evictEntityCache(kind)
+}
+
+//invalidate cache of tables written
+func (q *Query[T]) invalidateQueryCache() {
+    if queryCache == nil {
+        return
+    }
+    for _, v := range q.tables {
+        if v.rawSql == "" {
+            queryCache.Invalidate(v.getTableName())
+        }
+    }
+}
+
+//copy of value, not sharing pointers, slices, maps
+func deepCopyValue(v reflect.Value) reflect.Value {
+    switch v.Kind() {
+    case reflect.Ptr:
+        if v.IsNil() {
+            return v
+        }
+        ret := reflect.New(v.Type().Elem())
+        ret.Elem().Set(deepCopyValue(v.Elem()))
+        return ret
+    case reflect.Slice:
+        if v.IsNil() {
+            return v
+        }
+        ret := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+        for i := 0; i < v.Len(); i++ {
+            ret.Index(i).Set(deepCopyValue(v.Index(i)))
+        }
+        return ret
+    case reflect.Map:
+        if v.IsNil() {
+            return v
+        }
+        ret := reflect.MakeMapWithSize(v.Type(), v.Len())
+        iter := v.MapRange()
+        for iter.Next() {
+            ret.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+        }
+        return ret
+    case reflect.Struct:
+        ret := reflect.New(v.Type()).Elem()
+        ret.Set(v)
+        for i := 0; i < v.NumField(); i++ {
+            if ret.Field(i).CanSet() {
+                ret.Field(i).Set(deepCopyValue(v.Field(i)))
+            }
+        }
+        return ret
+    case reflect.Interface:
+        if v.IsNil() {
+            return v
+        }
+        ret := reflect.New(v.Type()).Elem()
+        ret.Set(deepCopyValue(v.Elem()))
+        return ret
+    }
+    return v
+}
+
+//in memory cache, least recently used entries removed beyond capacity
+type LRUQueryCache struct {
+    mu       sync.Mutex
+    capacity int
+    entries  map[string]*list.Element
+    order    *list.List
+    tables   map[string]map[string]struct{}
+}
+
+type lruQueryCacheEntry struct {
+    key      string
+    value    any
+    expireAt time.Time
+    tables   []string
+}
+
+func NewLRUQueryCache(capacity int) *LRUQueryCache {
+    return &LRUQueryCache{
+        capacity: capacity,
+        entries:  make(map[string]*list.Element),
+        order:    list.New(),
+        tables:   make(map[string]map[string]struct{}),
+    }
+}
+
+func (c *LRUQueryCache) Get(key string) (any, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    e, ok := c.entries[key]
+    if ok == false {
+        return nil, false
+    }
+    entry := e.Value.(*lruQueryCacheEntry)
+    if time.Now().After(entry.expireAt) {
+        c.remove(e)
+        return nil, false
+    }
+    c.order.MoveToFront(e)
+    return entry.value, true
+}
+
+func (c *LRUQueryCache) Set(key string, value any, ttl time.Duration, tables ...string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if e, ok := c.entries[key]; ok {
+        c.remove(e)
+    }
+    entry := &lruQueryCacheEntry{key: key, value: value, expireAt: time.Now().Add(ttl), tables: tables}
+    c.entries[key] = c.order.PushFront(entry)
+    for _, v := range tables {
+        if c.tables[v] == nil {
+            c.tables[v] = make(map[string]struct{})
+        }
+        c.tables[v][key] = struct{}{}
+    }
+    for c.capacity > 0 && c.order.Len() > c.capacity {
+        c.remove(c.order.Back())
+    }
+}
+
+func (c *LRUQueryCache) Delete(key string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if e, ok := c.entries[key]; ok {
+        c.remove(e)
+    }
+}
+
+func (c *LRUQueryCache) Invalidate(table string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for key := range c.tables[table] {
+        if e, ok := c.entries[key]; ok {
+            c.remove(e)
+        }
+    }
+    delete(c.tables, table)
+}
+
+func (c *LRUQueryCache) remove(e *list.Element) {
+    entry := e.Value.(*lruQueryCacheEntry)
+    c.order.Remove(e)
+    delete(c.entries, entry.key)
+    for _, v := range entry.tables {
+        delete(c.tables[v], entry.key)
+        if len(c.tables[v]) == 0 {
+            delete(c.tables, v)
+        }
+    }
+}
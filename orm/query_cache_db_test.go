@@ -0,0 +1,61 @@
+package orm_test
+
+import (
+    "database/sql"
+    "github.com/folospace/go-mysql-orm/orm"
+    "github.com/folospace/go-mysql-orm/orm/ormtest"
+    "testing"
+    "time"
+)
+
+type cacheUser struct {
+    Id   int    `json:"id"`
+    Name string `json:"name"`
+}
+
+func (*cacheUser) Connections() []*sql.DB {
+    return nil
+}
+
+func (*cacheUser) DatabaseName() string {
+    return "mydb"
+}
+
+func (*cacheUser) TableName() string {
+    return "user"
+}
+
+func TestQueryCacheOfDB(t *testing.T) {
+    orm.SetQueryCache(orm.NewLRUQueryCache(100))
+    defer orm.SetQueryCache(orm.NewLRUQueryCache(10000))
+
+    mockA := ormtest.New(t)
+    mockA.ExpectQuery(ormtest.Regexp("^select")).WillReturnRows([]string{"id", "name"}, []any{1, "john"})
+    mockB := ormtest.New(t)
+    mockB.ExpectQuery(ormtest.Regexp("^select")).WillReturnRows([]string{"id", "name"}, []any{1, "mary"})
+
+    table := new(cacheUser)
+    a, res := orm.NewQuery(table, mockA.DB()).Where(&table.Id, 1).Cache(time.Minute).Gets()
+    if res.Err != nil || len(a) != 1 || a[0].Name != "john" {
+        t.Fatalf("want john of db a, got %+v, %v", a, res.Err)
+    }
+    //same sql of other db not cached
+    b, res := orm.NewQuery(table, mockB.DB()).Where(&table.Id, 1).Cache(time.Minute).Gets()
+    if res.Err != nil || len(b) != 1 || b[0].Name != "mary" {
+        t.Fatalf("want mary of db b, got %+v, %v", b, res.Err)
+    }
+    //cached of db a
+    a, res = orm.NewQuery(table, mockA.DB()).Where(&table.Id, 1).Cache(time.Minute).Gets()
+    if res.Err != nil || len(a) != 1 || a[0].Name != "john" {
+        t.Errorf("want cached john of db a, got %+v, %v", a, res.Err)
+    }
+
+    for _, mock := range []*ormtest.Mock{mockA, mockB} {
+        if err := mock.ExpectationsWereMet(); err != nil {
+            t.Error(err)
+        }
+        if len(mock.Statements()) != 1 {
+            t.Errorf("want 1 select, got %v", mock.Statements())
+        }
+    }
+}
@@ -0,0 +1,242 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+//table read by subqueries of test_row
+type testRowTag struct {
+    Id    int    `json:"id"`
+    RowId int    `json:"row_id"`
+    Tag   string `json:"tag"`
+}
+
+func (*testRowTag) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testRowTag) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testRowTag) TableName() string {
+    return "test_row_tag"
+}
+
+//fresh query cache during test
+func withQueryCache(t *testing.T, capacity int) *LRUQueryCache {
+    c := NewLRUQueryCache(capacity)
+    old := queryCache
+    SetQueryCache(c)
+    t.Cleanup(func() {
+        queryCache = old
+    })
+    return c
+}
+
+//fake db with rows of test_row, counting selects
+func newCacheDB() (*sql.DB, func() int) {
+    db, fake := newFakeDB()
+    selects := 0
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        selects++
+        return &fakeRows{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "john"}, {int64(2), "mary"}}}, nil
+    }
+    return db, func() int {
+        return selects
+    }
+}
+
+func TestQueryCache(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+    table := new(testRow)
+
+    rows, res := NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2}).Cache(time.Minute).Gets()
+    if res.Err != nil || len(rows) != 2 || selects() != 1 {
+        t.Fatalf("want 2 rows queried, got %d rows, %d selects, %v", len(rows), selects(), res.Err)
+    }
+
+    //result copied, not shared with cache
+    rows[0].Name = "changed"
+
+    cached, res := NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2}).Cache(time.Minute).Gets()
+    if res.Err != nil || selects() != 1 {
+        t.Fatalf("want cache hit, got %d selects, %v", selects(), res.Err)
+    }
+    if res.RowsAffected != 2 || cached[0].Name != "john" {
+        t.Errorf("want cached rows unchanged, got %+v, rows affected %d", cached, res.RowsAffected)
+    }
+
+    //other bindings, other dest type, no cache
+    NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 3}).Cache(time.Minute).Gets()
+    var names []string
+    NewQuery(table, db).Select(&table.Name).Where(&table.Id, WhereIn, []int{1, 2}).Cache(time.Minute).GetTo(&names)
+    NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2}).Gets()
+    if selects() != 4 {
+        t.Errorf("want 4 selects, got %d", selects())
+    }
+}
+
+func TestQueryCacheInvalidatedByWrite(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+    table := new(testRow)
+
+    get := func() {
+        NewQuery(table, db).Where(&table.Id, 1).Cache(time.Minute).Gets()
+    }
+    get()
+    get()
+    if selects() != 1 {
+        t.Fatalf("want cache hit, got %d selects", selects())
+    }
+
+    NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+    get()
+    if selects() != 2 {
+        t.Errorf("want query after update, got %d selects", selects())
+    }
+
+    //cached read in transaction not used
+    NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        query.Where(&table.Id, 1).Cache(time.Minute).Gets()
+        return nil
+    })
+    if selects() != 3 {
+        t.Errorf("want query in transaction, got %d selects", selects())
+    }
+}
+
+func TestQueryCacheTTL(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+
+    NewQuery(new(testRow), db).Cache(time.Millisecond).Gets()
+    time.Sleep(5 * time.Millisecond)
+    NewQuery(new(testRow), db).Cache(time.Millisecond).Gets()
+    if selects() != 2 {
+        t.Errorf("want expired entry queried again, got %d selects", selects())
+    }
+}
+
+func TestLRUQueryCache(t *testing.T) {
+    c := NewLRUQueryCache(2)
+    c.Set("a", 1, time.Minute, "user")
+    c.Set("b", 2, time.Minute, "user", "order")
+    c.Get("a")
+    c.Set("c", 3, time.Minute, "order")
+
+    if _, ok := c.Get("b"); ok {
+        t.Error("want least recently used removed")
+    }
+    if v, ok := c.Get("a"); ok == false || v != 1 {
+        t.Errorf("want a kept, got %v", v)
+    }
+
+    c.Invalidate("order")
+    if _, ok := c.Get("c"); ok {
+        t.Error("want c invalidated by order")
+    }
+    if _, ok := c.Get("a"); ok == false {
+        t.Error("want a of user kept")
+    }
+    if len(c.tables) != 1 || c.order.Len() != 1 {
+        t.Errorf("want index of user only, got %v", c.tables)
+    }
+}
+
+func TestDeepCopyValue(t *testing.T) {
+    name := "john"
+    type row struct {
+        Name *string
+        Tags []string
+        Meta map[string]any
+    }
+    src := []row{{Name: &name, Tags: []string{"a"}, Meta: map[string]any{"k": []int{1}}}}
+
+    dst := deepCopyValue(reflect.ValueOf(src)).Interface().([]row)
+    if reflect.DeepEqual(src, dst) == false {
+        t.Fatalf("want equal copy, got %+v", dst)
+    }
+    *dst[0].Name = "mary"
+    dst[0].Tags[0] = "b"
+    dst[0].Meta["k"].([]int)[0] = 2
+    if name != "john" || src[0].Tags[0] != "a" || src[0].Meta["k"].([]int)[0] != 1 {
+        t.Errorf("want source unchanged, got %+v", src)
+    }
+}
+
+func TestQueryCacheExport(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+
+    for i := 0; i < 2; i++ {
+        var str strings.Builder
+        res := NewQuery(new(testRow), db).Cache(time.Minute).ExportCSV(&str)
+        if res.Err != nil || str.String() != "id,name\n1,john\n2,mary\n" {
+            t.Fatalf("want rows exported, got %q, %v", str.String(), res.Err)
+        }
+    }
+    if selects() != 2 {
+        t.Errorf("want export not cached, got %d selects", selects())
+    }
+}
+
+func TestQueryCacheSubqueryTables(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+    table, tag := new(testRow), new(testRowTag)
+
+    get := func() {
+        tagged := NewQuery(tag, db).Select(&tag.RowId).Where(&tag.Tag, "vip").SubQuery()
+        NewQuery(table, db).Where(&table.Id, WhereIn, tagged).Cache(time.Minute).Gets()
+    }
+    get()
+    get()
+    if selects() != 1 {
+        t.Fatalf("want cache hit, got %d selects", selects())
+    }
+
+    NewQuery(tag, db).Where(&tag.Id, 1).Update(&tag.Tag, "vip")
+    get()
+    if selects() != 2 {
+        t.Errorf("want invalidated by write of subquery table, got %d selects", selects())
+    }
+}
+
+func TestQueryCacheTransaction(t *testing.T) {
+    withQueryCache(t, 100)
+    db, selects := newCacheDB()
+    table := new(testRow)
+
+    get := func() {
+        NewQuery(table, db).Where(&table.Id, 1).Cache(time.Minute).Gets()
+    }
+    get()
+
+    NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        query.Where(&table.Id, 1).Update(&table.Name, "john")
+        //other connections still read old row until commit
+        get()
+        return errors.New("rollback")
+    })
+    get()
+    if selects() != 1 {
+        t.Errorf("want cache kept until commit and after rollback, got %d selects", selects())
+    }
+
+    NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        return query.Where(&table.Id, 1).Update(&table.Name, "john").Err
+    })
+    get()
+    if selects() != 2 {
+        t.Errorf("want invalidated after commit, got %d selects", selects())
+    }
+}
@@ -17,6 +17,7 @@ func (q *Query[T]) Delete(primaryIds ...any) QueryResult {
 
 func (q *Query[T]) delete() QueryResult {
     bindings := make([]any, 0)
+    q.requireShard()
 
     if len(q.wheres) == 0 && len(q.tables) <= 1 && q.limit == 0 {
         q.setErr(ErrDeleteWithoutCondition)
@@ -28,9 +29,16 @@ func (q *Query[T]) delete() QueryResult {
 
     orderLimitOffsetStr := q.getOrderAndLimitSqlStr()
 
+    q.setErr(q.checkPendingColumns())
+
     rawSql := "delete"
     if orderLimitOffsetStr == "" {
-        rawSql += " " + q.tables[0].getTableName()
+        if _, ok := q.tables[0].table.(ShardTable); ok {
+            //shard table aliased by its name, not the routed one
+            rawSql += " " + q.tables[0].getAliasOrTableName()
+        } else {
+            rawSql += " " + q.tables[0].getTableName()
+        }
     }
     rawSql += " from " + tableStr
 
@@ -41,8 +49,9 @@ func (q *Query[T]) delete() QueryResult {
         rawSql += " " + orderLimitOffsetStr
     }
 
-    q.prepareSql = rawSql
+    q.prepareSql = q.withSqlComment(rawSql)
     q.bindings = bindings
+    q.kind = QueryKindDelete
 
     return q.Execute()
 }
@@ -1,6 +1,7 @@
 package orm
 
 import (
+    "context"
     "database/sql"
 )
 
@@ -10,42 +11,61 @@ func (q *Query[T]) Execute() QueryResult {
         q.setErr(ErrRawSqlRequired)
     }
 
+    //kind set by insert, update, delete for this statement only
+    kind := q.kind
+    q.kind = ""
+    if kind == "" {
+        kind = QueryKindExecute
+    }
+
     q.result.PrepareSql = q.prepareSql
     q.result.Bindings = q.bindings
+    if q.dryRun {
+        return q.result
+    }
+
+    var db *sql.DB
+    if q.Tx() == nil {
+        db = q.DB()
+    }
 
     if q.result.Err != nil {
         if errorLogger != nil {
             errorLogger.Error(q.result.Sql(), q.result.Error())
         }
+        logStatement(q.context(), q.queryInfo(kind, nil, db), q.result, q.result.Err, 0)
         return q.result
     } else if infoLogger != nil {
         infoLogger.Info(q.result.Sql(), q.result.Error())
     }
 
-    var res sql.Result
-    var err error
-    if q.Tx() != nil {
-        if q.ctx != nil {
-            res, err = q.Tx().ExecContext(*q.ctx, q.prepareSql, q.bindings...)
-        } else {
-            res, err = q.Tx().Exec(q.prepareSql, q.bindings...)
-        }
-    } else {
-        if q.ctx != nil {
-            res, err = q.DB().ExecContext(*q.ctx, q.prepareSql, q.bindings...)
-        } else {
-            res, err = q.DB().Exec(q.prepareSql, q.bindings...)
-        }
-    }
-
+    res, err := q.runMiddlewares(q.context(), q.queryInfo(kind, nil, db), q.exec)
+    q.result = res
     if err != nil {
         q.result.Err = err
         if errorLogger != nil {
             errorLogger.Error(q.result.Sql(), q.result.Error())
         }
-    } else if res != nil {
-        q.result.LastInsertId, q.result.Err = res.LastInsertId()
-        q.result.RowsAffected, q.result.Err = res.RowsAffected()
+    } else {
+        markWritten(q.ctx)
+        q.evictCaches(kind)
     }
     return q.result
 }
+
+func (q *Query[T]) exec(ctx context.Context, info QueryInfo) (QueryResult, error) {
+    result := q.result
+    result.PrepareSql = info.Sql
+    result.Bindings = info.Bindings
+
+    return q.runStatement(ctx, info.db, func(runner queryRunner) (QueryResult, error) {
+        res, err := runner.ExecContext(ctx, info.Sql, info.Bindings...)
+        if err != nil {
+            return result, err
+        } else if res != nil {
+            result.LastInsertId, err = res.LastInsertId()
+            result.RowsAffected, err = res.RowsAffected()
+        }
+        return result, err
+    })
+}
@@ -0,0 +1,226 @@
+package orm
+
+import (
+    "bufio"
+    "database/sql"
+    "database/sql/driver"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "reflect"
+    "strconv"
+    "time"
+)
+
+const exportTimeFormat = "2006-01-02 15:04:05"
+
+//write selected rows as csv while scanning, first line is column names
+//NULL as empty, times as "2006-01-02 15:04:05" (JsonTime included), JsonField as json string
+func (q *Query[T]) ExportCSV(w io.Writer) QueryResult {
+    return q.getRows(nil, func(rows *sql.Rows) error {
+        columns, err := rows.Columns()
+        if err != nil {
+            return err
+        }
+        writer := csv.NewWriter(w)
+        if err = writer.Write(columns); err != nil {
+            return err
+        }
+
+        cells := make([]string, len(columns))
+        err = q.scanExportRows(rows, columns, func(values []any) error {
+            for k, v := range values {
+                cells[k] = exportCSVCell(v)
+            }
+            return writer.Write(cells)
+        })
+        if err != nil {
+            return err
+        }
+        writer.Flush()
+        return writer.Error()
+    })
+}
+
+//write selected rows as json lines while scanning, one json object per row
+//NULL as null, times as "2006-01-02 15:04:05" (JsonTime included) like csv, other fields of T by their json marshaler, like JsonInt, JsonField
+func (q *Query[T]) ExportJSONL(w io.Writer) QueryResult {
+    return q.getRows(nil, func(rows *sql.Rows) error {
+        columns, err := rows.Columns()
+        if err != nil {
+            return err
+        }
+        keys := make([][]byte, len(columns))
+        for k, v := range columns {
+            keys[k], err = json.Marshal(v)
+            if err != nil {
+                return err
+            }
+        }
+
+        writer := bufio.NewWriter(w)
+        err = q.scanExportRows(rows, columns, func(values []any) error {
+            _ = writer.WriteByte('{')
+            for k, v := range values {
+                if k > 0 {
+                    _ = writer.WriteByte(',')
+                }
+                _, _ = writer.Write(keys[k])
+                _ = writer.WriteByte(':')
+                data, err := json.Marshal(exportJSONValue(v))
+                if err != nil {
+                    return err
+                }
+                _, _ = writer.Write(data)
+            }
+            _, err := writer.WriteString("}\n")
+            return err
+        })
+        if err != nil {
+            return err
+        }
+        return writer.Flush()
+    })
+}
+
+//scan each row, values are nil for NULL, typed as field of T if column matched
+func (q *Query[T]) scanExportRows(rows *sql.Rows, columns []string, write func(values []any) error) error {
+    var err error
+    structAddrMap := make(map[string]any)
+    //no fields of subquery
+    if q.tables[0].tableStructType != nil {
+        structAddrMap, err = getStructFieldAddrMap(reflect.New(q.tables[0].tableStructType).Interface())
+        if err != nil {
+            return err
+        }
+    }
+
+    basePtrs := make([]any, len(columns))
+    tempPtrs := make([]any, len(columns))
+    for k, v := range columns {
+        var temp any
+        tempPtrs[k] = &temp
+        if fieldAddr, ok := structAddrMap[v]; ok {
+            basePtrs[k] = reflect.New(reflect.TypeOf(fieldAddr).Elem()).Interface()
+        } else {
+            basePtrs[k] = tempPtrs[k]
+        }
+    }
+
+    finalPtrs := make([]any, len(columns))
+    values := make([]any, len(columns))
+
+    for rows.Next() {
+        err = rows.Scan(tempPtrs...)
+        if err != nil {
+            return err
+        }
+        for k, v := range tempPtrs {
+            if *v.(*any) == nil {
+                finalPtrs[k] = v
+            } else {
+                finalPtrs[k] = basePtrs[k]
+            }
+        }
+        err = rows.Scan(finalPtrs...)
+        if err != nil {
+            return err
+        }
+
+        for k, v := range finalPtrs {
+            values[k] = reflect.ValueOf(v).Elem().Interface()
+        }
+        q.result.RowsAffected += 1
+
+        if err = write(values); err != nil {
+            return err
+        }
+    }
+    return rows.Err()
+}
+
+func exportCSVCell(v any) string {
+    switch val := v.(type) {
+    case nil:
+        return ""
+    case string:
+        return val
+    case []byte:
+        return string(val)
+    case time.Time:
+        if val.IsZero() {
+            return ""
+        }
+        return val.Format(exportTimeFormat)
+    case JsonTime:
+        return exportCSVCell(val.Time)
+    case *JsonTime:
+        if val == nil {
+            return ""
+        }
+        return exportCSVCell(val.Time)
+    case JsonInt:
+        return val.ToString()
+    case bool:
+        return strconv.FormatBool(val)
+    case float32:
+        return strconv.FormatFloat(float64(val), 'f', -1, 32)
+    case float64:
+        return strconv.FormatFloat(val, 'f', -1, 64)
+    case json.Marshaler:
+        //JsonField
+        if reflect.ValueOf(val).Kind() == reflect.Ptr && reflect.ValueOf(val).IsNil() {
+            return ""
+        }
+        data, err := val.MarshalJSON()
+        if err != nil {
+            return ""
+        }
+        return string(data)
+    case driver.Valuer:
+        if reflect.ValueOf(val).Kind() == reflect.Ptr && reflect.ValueOf(val).IsNil() {
+            return ""
+        }
+        temp, err := val.Value()
+        if err != nil {
+            return ""
+        }
+        return exportCSVCell(temp)
+    default:
+        rv := reflect.ValueOf(v)
+        if rv.Kind() == reflect.Ptr {
+            if rv.IsNil() {
+                return ""
+            }
+            return exportCSVCell(rv.Elem().Interface())
+        }
+        return fmt.Sprint(v)
+    }
+}
+
+//raw bytes as string, times as csv, others by json marshaler
+func exportJSONValue(v any) any {
+    switch val := v.(type) {
+    case []byte:
+        return string(val)
+    case time.Time:
+        if val.IsZero() {
+            return nil
+        }
+        return val.Format(exportTimeFormat)
+    case *time.Time:
+        if val == nil {
+            return nil
+        }
+        return exportJSONValue(*val)
+    case JsonTime:
+        return exportJSONValue(val.Time)
+    case *JsonTime:
+        if val == nil {
+            return nil
+        }
+        return exportJSONValue(val.Time)
+    }
+    return v
+}
@@ -0,0 +1,105 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "strings"
+    "testing"
+    "time"
+)
+
+type testEvent struct {
+    Id        JsonInt             `json:"id"`
+    Name      *string             `json:"name"`
+    CreatedAt time.Time           `json:"created_at"`
+    UpdatedAt JsonTime            `json:"updated_at"`
+    DeletedAt *JsonTime           `json:"deleted_at"`
+    Tags      JsonField[[]string] `json:"tags"`
+}
+
+func (*testEvent) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testEvent) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testEvent) TableName() string {
+    return "test_event"
+}
+
+func newExportDB() *sql.DB {
+    created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return &fakeRows{
+            columns: []string{"id", "name", "created_at", "updated_at", "deleted_at", "tags", "note"},
+            rows: [][]driver.Value{
+                {int64(1), []byte("john"), created, created, created, []byte(`["a","b"]`), []byte("x,y")},
+                {int64(2), nil, nil, nil, nil, nil, nil},
+            },
+        }, nil
+    }
+    return db
+}
+
+func TestExportCSV(t *testing.T) {
+    var str strings.Builder
+    res := NewQuery(new(testEvent), newExportDB()).ExportCSV(&str)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    want := "id,name,created_at,updated_at,deleted_at,tags,note\n" +
+        "1,john,2024-01-02 03:04:05,2024-01-02 03:04:05,2024-01-02 03:04:05,\"[\"\"a\"\",\"\"b\"\"]\",\"x,y\"\n" +
+        "2,,,,,,\n"
+    if str.String() != want {
+        t.Errorf("want:\n%s\ngot:\n%s", want, str.String())
+    }
+    if res.RowsAffected != 2 {
+        t.Errorf("want 2 rows, got %d", res.RowsAffected)
+    }
+}
+
+func TestExportJSONL(t *testing.T) {
+    var str strings.Builder
+    res := NewQuery(new(testEvent), newExportDB()).ExportJSONL(&str)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    want := `{"id":"1","name":"john","created_at":"2024-01-02 03:04:05","updated_at":"2024-01-02 03:04:05",` +
+        `"deleted_at":"2024-01-02 03:04:05","tags":["a","b"],"note":"x,y"}` + "\n" +
+        `{"id":"2","name":null,"created_at":null,"updated_at":null,"deleted_at":null,"tags":null,"note":null}` + "\n"
+    if str.String() != want {
+        t.Errorf("want:\n%s\ngot:\n%s", want, str.String())
+    }
+}
+
+func TestExportSubQuery(t *testing.T) {
+    db := newExportDB()
+    sub := NewQuery(new(testEvent), db).SubQuery()
+
+    var str strings.Builder
+    res := NewQuery(sub, db).ExportCSV(&str)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    want := "id,name,created_at,updated_at,deleted_at,tags,note\n" +
+        "1,john,2024-01-02 03:04:05,2024-01-02 03:04:05,2024-01-02 03:04:05,\"[\"\"a\"\",\"\"b\"\"]\",\"x,y\"\n" +
+        "2,,,,,,\n"
+    if str.String() != want {
+        t.Errorf("want:\n%s\ngot:\n%s", want, str.String())
+    }
+
+    str.Reset()
+    res = NewQuery(sub, db).ExportJSONL(&str)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    want = `{"id":1,"name":"john","created_at":"2024-01-02 03:04:05","updated_at":"2024-01-02 03:04:05",` +
+        `"deleted_at":"2024-01-02 03:04:05","tags":"[\"a\",\"b\"]","note":"x,y"}` + "\n" +
+        `{"id":2,"name":null,"created_at":null,"updated_at":null,"deleted_at":null,"tags":null,"note":null}` + "\n"
+    if str.String() != want {
+        t.Errorf("want:\n%s\ngot:\n%s", want, str.String())
+    }
+}
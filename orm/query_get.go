@@ -1,6 +1,7 @@
 package orm
 
 import (
+    "context"
     "database/sql"
     "reflect"
     "strings"
@@ -9,6 +10,15 @@ import (
 //get first T
 func (q *Query[T]) Get(primaryIds ...any) (T, QueryResult) {
     ret := reflect.New(q.tables[0].tableStructType).Interface()
+
+    if ttl, ok := q.entityCacheTTL(); ok && len(primaryIds) == 1 && reflect.ValueOf(primaryIds[0]).Kind() != reflect.Slice {
+        rows, res := q.getsFromEntityCache(primaryIds, ttl)
+        if len(rows) > 0 {
+            return rows[0], res
+        }
+        return ret.(T), res
+    }
+
     var res QueryResult
 
     if len(primaryIds) == 1 {
@@ -26,6 +36,9 @@ func (q *Query[T]) Get(primaryIds ...any) (T, QueryResult) {
 func (q *Query[T]) Gets(primaryIds ...any) ([]T, QueryResult) {
     var ret []T
     var res QueryResult
+    if ttl, ok := q.entityCacheTTL(); ok && len(primaryIds) > 0 {
+        return q.getsFromEntityCache(entityCacheIds(primaryIds), ttl)
+    }
     if len(primaryIds) == 1 {
         res = q.WherePrimary(primaryIds[0]).GetTo(&ret)
     } else if len(primaryIds) > 0 {
@@ -106,6 +119,9 @@ func (q *Query[T]) GetCount() (int64, QueryResult) {
             return q.setErr(err).Select(c).GetInt()
         }
     } else {
+        if q.needScatter() {
+            return 0, q.setErr(ErrShardKeyRequired).result
+        }
         tempTable := q.SubQuery()
 
         newQuery := NewQuery(tempTable, tempTable.dbs...)
@@ -119,54 +135,76 @@ func (q *Query[T]) GetCount() (int64, QueryResult) {
    value, []value, map[key]value, map[key][]value
 */
 func (q *Query[T]) GetTo(destPtr any) QueryResult {
+    if q.needScatter() {
+        return q.scatterGetTo(destPtr)
+    }
+    return q.getRows(destPtr, func(rows *sql.Rows) error {
+        return q.scanRows(destPtr, rows)
+    })
+}
+
+//query and scan rows by scan func
+func (q *Query[T]) getRows(dest any, scan func(rows *sql.Rows) error) QueryResult {
     tempTable := q.SubQuery()
 
-    q.result.PrepareSql = tempTable.raw
+    q.result.PrepareSql = q.withSqlComment(tempTable.raw)
     q.result.Bindings = tempTable.bindings
     if tempTable.err != nil {
         q.result.Err = tempTable.err
     }
 
+    var db *sql.DB
+    if q.Tx() == nil {
+        db = q.readDB()
+    }
+
     if q.result.Err != nil {
         if errorLogger != nil {
             errorLogger.Error(q.result.Sql(), q.result.Error())
         }
+        logStatement(q.context(), q.queryInfo(QueryKindSelect, dest, db), q.result, q.result.Err, 0)
         return q.result
-    } else if infoLogger != nil {
-        infoLogger.Info(q.result.Sql(), q.result.Error())
     }
 
-    var rows *sql.Rows
-    var err error
-    if q.Tx() != nil {
-        if q.ctx != nil {
-            rows, err = q.Tx().QueryContext(*q.ctx, tempTable.raw, tempTable.bindings...)
-        } else {
-            rows, err = q.Tx().Query(tempTable.raw, tempTable.bindings...)
-        }
-    } else {
-        if q.ctx != nil {
-            rows, err = q.readDB().QueryContext(*q.ctx, tempTable.raw, tempTable.bindings...)
-        } else {
-            rows, err = q.readDB().Query(tempTable.raw, tempTable.bindings...)
+    //key without sql comment, rows streamed without dest (exports) not cached
+    cacheKey := ""
+    if q.useQueryCache() && dest != nil {
+        cacheKey = queryCacheKey(q.writeDB(), dest, tempTable)
+        if q.getCachedResult(cacheKey, dest) {
+            return q.result
         }
     }
+    if infoLogger != nil {
+        infoLogger.Info(q.result.Sql(), q.result.Error())
+    }
 
-    defer func() {
-        if rows != nil {
-            _ = rows.Close()
-        }
-    }()
+    res, err := q.runMiddlewares(q.context(), q.queryInfo(QueryKindSelect, dest, db), func(ctx context.Context, info QueryInfo) (QueryResult, error) {
+        q.result.PrepareSql = info.Sql
+        q.result.Bindings = info.Bindings
 
-    if err != nil {
-        q.result.Err = err
-        if errorLogger != nil {
-            errorLogger.Error(q.result.Sql(), q.result.Error())
-        }
-        return q.result
-    }
+        return q.runStatement(ctx, info.db, func(runner queryRunner) (QueryResult, error) {
+            rows, err := runner.QueryContext(ctx, info.Sql, info.Bindings...)
+
+            defer func() {
+                if rows != nil {
+                    _ = rows.Close()
+                }
+            }()
 
-    q.result.Err = q.scanRows(destPtr, rows)
+            if err == nil {
+                err = scan(rows)
+            }
+            return q.result, err
+        })
+    })
+
+    q.result = res
+    q.result.Err = err
+    if err != nil && errorLogger != nil {
+        errorLogger.Error(q.result.Sql(), q.result.Error())
+    } else if err == nil && cacheKey != "" {
+        q.setCachedResult(cacheKey, dest)
+    }
     return q.result
 }
 
@@ -4,18 +4,50 @@ import (
     "errors"
     "reflect"
     "sort"
+    "strconv"
     "strings"
 )
 
-//insert and set primary for first T
+const insertRowAlias = "new"
+
+//insert and set primary for T
+//rows are split into batches if too many placeholders or too large for max_allowed_packet,
+//batches inserted in one transaction if not in Transaction
 func (q *Query[T]) Insert(data ...T) QueryResult {
-    return q.insert(data)
+    return q.insertInShards(data, 0)
+}
+
+//insert at most size rows per statement, batches in one transaction if not in Transaction
+func (q *Query[T]) InsertInBatches(size int, data ...T) QueryResult {
+    return q.insertInShards(data, size)
 }
 
 func (q *Query[T]) InsertSubquery(data *SubQuery) QueryResult {
     return q.insert(data)
 }
 
+//insert ignore, RowsIgnored of result is the count of rows already existed
+func (q *Query[T]) InsertIgnore(data ...T) QueryResult {
+    q.insertIgnore = true
+    return q.insertInShards(data, 0)
+}
+
+func (q *Query[T]) InsertIgnoreSubquery(data *SubQuery) QueryResult {
+    q.insertIgnore = true
+    return q.insert(data)
+}
+
+//replace into, RowsReplaced of result is the count of rows deleted and inserted again
+func (q *Query[T]) Replace(data ...T) QueryResult {
+    q.replace = true
+    return q.insertInShards(data, 0)
+}
+
+func (q *Query[T]) ReplaceSubquery(data *SubQuery) QueryResult {
+    q.replace = true
+    return q.insert(data)
+}
+
 func (q *Query[T]) OnConflictUpdate(column any, val any, columnVars ...any) *Query[T] {
     q.insertIgnore = true
     q.conflictUpdates = []updateColumn{{col: column, val: val}}
@@ -44,6 +76,65 @@ func (q *Query[T]) OnConflictUpdates(columnVars map[any]any) *Query[T] {
     return q
 }
 
+//use "insert ... as new on duplicate key update col = new.col" instead of "col = values(col)",
+//detected by server version (mysql 8.0.19+) once per query if not set
+func (q *Query[T]) UseRowAlias(use bool) *Query[T] {
+    q.useRowAlias = &use
+    return q
+}
+
+//value of column in the row to insert, for on duplicate key update expressions like:
+//OnConflictUpdate(&T.Counter, orm.Raw("`counter` + ") + q.InsertedValue(&T.Counter))
+//insert of subquery needs UseRowAlias(false), as row alias not allowed by insert ... select
+func (q *Query[T]) InsertedValue(column any) Raw {
+    c, err := q.parseColumn(column)
+    if err != nil {
+        q.setErr(err)
+        return ""
+    }
+    if q.rowAliasEnabled() {
+        q.rowAliasValue = true
+        return Raw(insertRowAlias + ".`" + columnName(c) + "`")
+    }
+    return Raw("values(`" + columnName(c) + "`)")
+}
+
+func (q *Query[T]) insertedColumn(column string) Raw {
+    if q.rowAlias != "" {
+        return Raw(q.rowAlias + ".`" + columnName(column) + "`")
+    }
+    return Raw("values(`" + columnName(column) + "`)")
+}
+
+//decided once, same for InsertedValue and insert, version read through tx if in transaction
+func (q *Query[T]) rowAliasEnabled() bool {
+    if q.useRowAlias == nil {
+        use := false
+        //nothing queried by dry run, values(col) valid of all versions
+        if q.dryRun == false {
+            version, err := getServerVariable(q.writeDB(), q.Tx(), "version")
+            use = err == nil && versionSupportRowAlias(version)
+        }
+        q.useRowAlias = &use
+    }
+    return *q.useRowAlias
+}
+
+//mysql 8.0.19+, not mariadb
+func versionSupportRowAlias(version string) bool {
+    if strings.Contains(strings.ToLower(version), "mariadb") {
+        return false
+    }
+    var nums [3]int
+    for k, v := range strings.SplitN(strings.SplitN(version, "-", 2)[0], ".", 3) {
+        nums[k], _ = strconv.Atoi(v)
+    }
+    if nums[0] != 8 {
+        return nums[0] > 8
+    }
+    return nums[1] > 0 || nums[2] >= 19
+}
+
 func (q *Query[T]) gennerateInsertSql(InsertColumns []string, rowCount int) string {
     columnRawStr := ""
     valRawStr := ""
@@ -122,6 +213,9 @@ func (q *Query[T]) insert(data any) QueryResult {
     } else {
         q.setErr(errors.New("data must be subquery or slice of T"))
     }
+    if q.result.Err == nil {
+        q.routeInsertShard(data)
+    }
 
     if q.result.Err != nil {
         if errorLogger != nil {
@@ -172,10 +266,24 @@ func (q *Query[T]) insert(data any) QueryResult {
         bindings = q.getInsertBindings(val, validFieldIndex, structDefaults)
     }
 
+    q.rowAlias = ""
+    if isSubQuery && q.rowAliasValue {
+        q.setErr(ErrRowAliasOfSubQuery)
+    } else if isSubQuery == false && q.replace == false && len(updates) > 0 && q.rowAliasEnabled() {
+        q.rowAlias = insertRowAlias
+        insertSql += " as " + q.rowAlias
+    }
     updateStr = q.generateUpdateStr(updates, &bindings)
+    q.rowAlias = ""
+    q.setErr(q.checkPendingColumns())
 
     rawSql := "insert"
-    if ignore {
+    if q.replace {
+        if updateStr != "" {
+            q.setErr(ErrReplaceWithConflictUpdate)
+        }
+        rawSql = "replace"
+    } else if ignore {
         rawSql += " ignore"
     }
 
@@ -187,14 +295,79 @@ func (q *Query[T]) insert(data any) QueryResult {
 
     rawSql += ";"
 
-    q.prepareSql = rawSql
+    q.prepareSql = q.withSqlComment(rawSql)
     q.bindings = bindings
+    q.kind = QueryKindInsert
 
     res := q.Execute()
 
-    //set first element's first field on condition
+    if res.Err == nil {
+        q.setInsertedRows(rowCount, isSubQuery == false, updateStr != "")
+        res = q.result
+    }
+
+    //set primary of inserted elements on condition
     if isSubQuery == false && res.Err == nil && res.LastInsertId > 0 && (val.Len() == 1 || q.insertIgnore == false) {
-        val.Index(0).Elem().Field(0).Set(reflect.ValueOf(res.LastInsertId).Convert(val.Index(0).Elem().Field(0).Type()))
+        q.result.InsertIds = q.setInsertIds(val, res.LastInsertId)
+        res = q.result
     }
     return res
 }
+
+//inserted, ignored, replaced rows by rows affected, rowCount is unknown for subquery
+func (q *Query[T]) setInsertedRows(rowCount int, rowCountKnown bool, isUpsert bool) {
+    affected := q.result.RowsAffected
+    if isUpsert {
+        //1 for inserted, 2 for updated, 0 for unchanged
+        return
+    }
+
+    if q.replace {
+        //2 for each replaced row: deleted then inserted
+        if rowCountKnown == false {
+            return
+        }
+        replaced := affected - int64(rowCount)
+        if replaced < 0 {
+            replaced = 0
+        } else if replaced > int64(rowCount) {
+            replaced = int64(rowCount)
+        }
+        q.result.RowsReplaced = replaced
+        q.result.RowsInserted = int64(rowCount) - replaced
+        return
+    }
+
+    q.result.RowsInserted = affected
+    if q.insertIgnore && rowCountKnown && int64(rowCount) > affected {
+        q.result.RowsIgnored = int64(rowCount) - affected
+    }
+}
+
+//set auto increment ids, consecutive ids for multi rows (innodb), step by auto_increment_increment
+func (q *Query[T]) setInsertIds(val reflect.Value, firstId int64) []int64 {
+    if isIntegerKind(val.Index(0).Elem().Field(0).Kind()) == false {
+        return nil
+    }
+
+    for i := 0; i < val.Len(); i++ {
+        if val.Index(i).Elem().Field(0).IsZero() == false {
+            //primary given by caller, ids are not consecutive, rows untouched
+            return nil
+        }
+    }
+
+    if val.Len() == 1 {
+        val.Index(0).Elem().Field(0).Set(reflect.ValueOf(firstId).Convert(val.Index(0).Elem().Field(0).Type()))
+        return []int64{firstId}
+    }
+
+    increment := getServerVariableInt(q.DB(), q.Tx(), "auto_increment_increment", 1)
+
+    ids := make([]int64, val.Len())
+    for i := range ids {
+        ids[i] = firstId + int64(i)*increment
+        val.Index(i).Elem().Field(0).Set(reflect.ValueOf(ids[i]).Convert(val.Index(i).Elem().Field(0).Type()))
+    }
+    return ids
+}
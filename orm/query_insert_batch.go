@@ -0,0 +1,181 @@
+package orm
+
+import (
+    "database/sql/driver"
+    "reflect"
+    "time"
+)
+
+const maxPlaceholders = 65535
+const defaultMaxAllowedPacket = 4 << 20
+
+//packet size is checked only if estimated size of all rows reach this
+const checkPacketSizeThreshold = 1 << 20
+
+func (q *Query[T]) insertInBatches(data []T, size int) QueryResult {
+    batches := q.splitInsertRows(data, size)
+    if len(batches) <= 1 {
+        return q.insert(data)
+    }
+
+    if q.tx != nil {
+        q.result = q.insertBatches(batches)
+        return q.result
+    }
+
+    //all batches or none
+    zeroIds := zeroPrimaryRows(data)
+    var result QueryResult
+    err := q.Clone().Transaction(func(tq *Query[T]) error {
+        result = tq.insertBatches(batches)
+        return result.Err
+    })
+    if err != nil {
+        //ids of rolled back rows cleared
+        for _, v := range zeroIds {
+            v.Set(reflect.Zero(v.Type()))
+        }
+        result = QueryResult{PrepareSql: result.PrepareSql, Bindings: result.Bindings, Err: err}
+    }
+
+    q.result = result
+    return q.result
+}
+
+func (q *Query[T]) insertBatches(batches [][]T) QueryResult {
+    var result QueryResult
+    for _, v := range batches {
+        nq := q.Clone()
+        res := nq.insert(v)
+
+        mergeInsertResult(&result, res)
+        if res.Err != nil {
+            break
+        }
+    }
+    return result
+}
+
+//primary fields of rows not given by caller
+func zeroPrimaryRows[T Table](data []T) []reflect.Value {
+    var ret []reflect.Value
+    for _, v := range data {
+        rowVal := reflect.ValueOf(v)
+        if rowVal.Kind() == reflect.Ptr && rowVal.IsNil() == false && rowVal.Elem().Kind() == reflect.Struct &&
+            rowVal.Elem().NumField() > 0 && rowVal.Elem().Field(0).IsZero() {
+            ret = append(ret, rowVal.Elem().Field(0))
+        }
+    }
+    return ret
+}
+
+func mergeInsertResult(result *QueryResult, res QueryResult) {
+    result.PrepareSql = res.PrepareSql
+    result.Bindings = res.Bindings
+    if result.LastInsertId == 0 {
+        result.LastInsertId = res.LastInsertId
+    }
+    result.InsertIds = append(result.InsertIds, res.InsertIds...)
+    result.RowsAffected += res.RowsAffected
+    result.RowsInserted += res.RowsInserted
+    result.RowsIgnored += res.RowsIgnored
+    result.RowsReplaced += res.RowsReplaced
+    if res.Err != nil {
+        result.Err = res.Err
+    }
+}
+
+//split rows by row count, placeholder count and estimated bytes
+func (q *Query[T]) splitInsertRows(data []T, size int) [][]T {
+    if len(data) <= 1 {
+        return [][]T{data}
+    }
+
+    val := reflect.ValueOf(data)
+    if val.Index(0).IsNil() || val.Index(0).Type().Elem() != q.tables[0].tableStructType {
+        return [][]T{data}
+    }
+    fieldNames, err := getStructFieldNameSlice(val.Index(0).Elem().Interface())
+    if err != nil {
+        return [][]T{data}
+    }
+
+    var fieldIndexes []int
+    for k, v := range fieldNames {
+        if v != "" {
+            fieldIndexes = append(fieldIndexes, k)
+        }
+    }
+    if len(fieldIndexes) == 0 {
+        return [][]T{data}
+    }
+
+    maxRows := maxPlaceholders / len(fieldIndexes)
+    if size > 0 && size < maxRows {
+        maxRows = size
+    }
+
+    rowSizes := make([]int, len(data))
+    totalSize := 0
+    for i := range data {
+        row := val.Index(i)
+        if row.IsNil() {
+            return [][]T{data}
+        }
+        for _, k := range fieldIndexes {
+            rowSizes[i] += estimateBindingSize(row.Elem().Field(k).Interface()) + 2
+        }
+        totalSize += rowSizes[i]
+    }
+
+    maxBytes := 0
+    if totalSize >= checkPacketSizeThreshold {
+        maxBytes = int(getServerVariableInt(q.writeDB(), q.Tx(), "max_allowed_packet", defaultMaxAllowedPacket)) / 10 * 9
+    }
+
+    var ret [][]T
+    start, bytes := 0, 0
+    for i := range data {
+        if i > start && (i-start >= maxRows || (maxBytes > 0 && bytes+rowSizes[i] > maxBytes)) {
+            ret = append(ret, data[start:i])
+            start, bytes = i, 0
+        }
+        bytes += rowSizes[i]
+    }
+    return append(ret, data[start:])
+}
+
+func estimateBindingSize(v any) int {
+    switch val := v.(type) {
+    case nil:
+        return 4
+    case string:
+        return len(val)
+    case []byte:
+        return len(val)
+    case time.Time:
+        return 26
+    case driver.Valuer:
+        rv := reflect.ValueOf(val)
+        if rv.Kind() == reflect.Ptr && rv.IsNil() {
+            return 4
+        }
+        temp, err := val.Value()
+        if err != nil {
+            return 8
+        }
+        return estimateBindingSize(temp)
+    default:
+        rv := reflect.ValueOf(v)
+        if rv.Kind() == reflect.Ptr {
+            if rv.IsNil() {
+                return 4
+            }
+            return estimateBindingSize(rv.Elem().Interface())
+        }
+        if rv.Kind() == reflect.String {
+            return rv.Len()
+        }
+        return 8
+    }
+}
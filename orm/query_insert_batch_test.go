@@ -0,0 +1,151 @@
+package orm
+
+import (
+    "database/sql/driver"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestSplitInsertRows(t *testing.T) {
+    rows := func(n int, name string) []*testRow {
+        ret := make([]*testRow, n)
+        for k := range ret {
+            ret[k] = &testRow{Name: name}
+        }
+        return ret
+    }
+    //3 columns, 65535 placeholders
+    maxRows := maxPlaceholders / 3
+    //1M per row, 4M max_allowed_packet by default, 90% of it used
+    large := strings.Repeat("a", 1<<20)
+
+    tests := []struct {
+        name string
+        rows []*testRow
+        size int
+        want []int
+    }{
+        {name: "empty", rows: nil, want: []int{0}},
+        {name: "one row", rows: rows(1, "a"), want: []int{1}},
+        {name: "under size", rows: rows(3, "a"), size: 5, want: []int{3}},
+        {name: "by size", rows: rows(5, "a"), size: 2, want: []int{2, 2, 1}},
+        {name: "by placeholders", rows: rows(maxRows+1, "a"), want: []int{maxRows, 1}},
+        {name: "size over placeholders", rows: rows(maxRows+1, "a"), size: maxRows + 1, want: []int{maxRows, 1}},
+        {name: "by packet size", rows: rows(5, large), want: []int{3, 2}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            batches := NewQuery(new(testRow)).splitInsertRows(tt.rows, tt.size)
+            got := make([]int, len(batches))
+            for k, v := range batches {
+                got[k] = len(v)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("want batches %v, got %v", tt.want, got)
+            }
+            for k := range got {
+                if got[k] != tt.want[k] {
+                    t.Errorf("want batches %v, got %v", tt.want, got)
+                    break
+                }
+            }
+        })
+    }
+}
+
+func TestSplitInsertRowsByMaxAllowedPacket(t *testing.T) {
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows("@@max_allowed_packet", "67108864"), nil
+    }
+
+    //90% of 64M, rows of 1M in one batch
+    rows := make([]*testRow, 5)
+    for k := range rows {
+        rows[k] = &testRow{Name: strings.Repeat("a", 1<<20)}
+    }
+    batches := NewQuery(new(testRow), db).splitInsertRows(rows, 0)
+    if len(batches) != 1 {
+        t.Errorf("want 1 batch, got %d", len(batches))
+    }
+}
+
+func TestInsertInBatches(t *testing.T) {
+    db, fake := newFakeDB()
+    var inserts int64
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        inserts++
+        return fakeResult{lastInsertId: inserts*10 + 1, rowsAffected: int64(len(args) / 3)}, nil
+    }
+
+    rows := []*testRow{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+    res := NewQuery(new(testRow), db).InsertInBatches(2, rows...)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if inserts != 2 {
+        t.Fatalf("want 2 statements, got %v", fake.recorded())
+    }
+    if res.RowsAffected != 3 || res.LastInsertId != 11 {
+        t.Errorf("want 3 rows affected, last insert id 11, got %d, %d", res.RowsAffected, res.LastInsertId)
+    }
+    if want := []int64{11, 12, 21}; reflect.DeepEqual(res.InsertIds, want) == false {
+        t.Errorf("want ids %v, got %v", want, res.InsertIds)
+    }
+}
+
+func TestInsertInBatchesTransaction(t *testing.T) {
+    t.Run("all batches committed", func(t *testing.T) {
+        db, fake := newFakeDB()
+        fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+            return fakeResult{lastInsertId: 1, rowsAffected: int64(len(args) / 3)}, nil
+        }
+        res := NewQuery(new(testRow), db).InsertInBatches(2, &testRow{Name: "a"}, &testRow{Name: "b"}, &testRow{Name: "c"})
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        if got := fake.transactions(); reflect.DeepEqual(got, []string{"begin", "commit"}) == false {
+            t.Errorf("want batches in one transaction, got %v", got)
+        }
+    })
+
+    t.Run("none on error", func(t *testing.T) {
+        db, fake := newFakeDB()
+        fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+            if len(fake.recorded()) > 1 {
+                return nil, errors.New("duplicate entry")
+            }
+            return fakeResult{lastInsertId: 11, rowsAffected: 2}, nil
+        }
+        rows := []*testRow{{Name: "a"}, {Id: 5, Name: "b"}, {Name: "c"}}
+        res := NewQuery(new(testRow), db).InsertInBatches(2, rows...)
+        if res.Err == nil || res.RowsAffected != 0 || len(res.InsertIds) != 0 {
+            t.Fatalf("want error without rows, got %+v", res)
+        }
+        if got := fake.transactions(); reflect.DeepEqual(got, []string{"begin", "rollback"}) == false {
+            t.Errorf("want rolled back, got %v", got)
+        }
+        if rows[0].Id != 0 || rows[1].Id != 5 || rows[2].Id != 0 {
+            t.Errorf("want ids of rolled back rows cleared, given kept, got %d %d %d", rows[0].Id, rows[1].Id, rows[2].Id)
+        }
+    })
+
+    t.Run("in Transaction", func(t *testing.T) {
+        db, fake := newFakeDB()
+        err := NewQuery(new(testRow), db).Transaction(func(query *Query[*testRow]) error {
+            return query.InsertInBatches(1, &testRow{Name: "a"}, &testRow{Name: "b"}).Err
+        })
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := fake.transactions(); reflect.DeepEqual(got, []string{"begin", "commit"}) == false {
+            t.Errorf("want no nested transaction, got %v", got)
+        }
+        if len(fake.recorded()) != 2 {
+            t.Errorf("want 2 inserts, got %v", fake.recorded())
+        }
+    })
+}
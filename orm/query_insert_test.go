@@ -0,0 +1,307 @@
+package orm
+
+import (
+    "database/sql/driver"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestSetInsertIds(t *testing.T) {
+    tests := []struct {
+        name    string
+        ids     []int
+        firstId int64
+        wantIds []int64
+        want    []int
+    }{
+        {name: "one row", ids: []int{0}, firstId: 10, wantIds: []int64{10}, want: []int{10}},
+        {name: "consecutive", ids: []int{0, 0, 0}, firstId: 10, wantIds: []int64{10, 11, 12}, want: []int{10, 11, 12}},
+        {name: "one row given", ids: []int{7}, firstId: 7, want: []int{7}},
+        {name: "first given", ids: []int{7, 0}, firstId: 7, want: []int{7, 0}},
+        {name: "last given", ids: []int{0, 7}, firstId: 10, want: []int{0, 7}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rows := make([]*testRow, len(tt.ids))
+            for k, v := range tt.ids {
+                rows[k] = &testRow{Id: v}
+            }
+
+            ids := NewQuery(new(testRow)).setInsertIds(reflect.ValueOf(rows), tt.firstId)
+            if reflect.DeepEqual(ids, tt.wantIds) == false {
+                t.Errorf("want ids %v, got %v", tt.wantIds, ids)
+            }
+            for k, v := range rows {
+                if v.Id != tt.want[k] {
+                    t.Errorf("row %d: want id %d, got %d", k, tt.want[k], v.Id)
+                }
+            }
+        })
+    }
+}
+
+func TestInsertIdsByAutoIncrementIncrement(t *testing.T) {
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows(strings.TrimPrefix(sqlStr, "select "), "2"), nil
+    }
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        return fakeResult{lastInsertId: 11, rowsAffected: 3}, nil
+    }
+
+    rows := []*testRow{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+    res := NewQuery(new(testRow), db).Insert(rows...)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if want := []int64{11, 13, 15}; reflect.DeepEqual(res.InsertIds, want) == false {
+        t.Errorf("want ids %v, got %v", want, res.InsertIds)
+    }
+    if rows[0].Id != 11 || rows[1].Id != 13 || rows[2].Id != 15 {
+        t.Errorf("want rows filled by ids, got %d %d %d", rows[0].Id, rows[1].Id, rows[2].Id)
+    }
+}
+
+func TestInsertIdsInTransaction(t *testing.T) {
+    db, fake := newFakeDB()
+    //connection of transaction is the only one
+    db.SetMaxOpenConns(1)
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows(strings.TrimPrefix(sqlStr, "select "), "2"), nil
+    }
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        return fakeResult{lastInsertId: 11, rowsAffected: 2}, nil
+    }
+
+    done := make(chan QueryResult, 1)
+    go func() {
+        var res QueryResult
+        NewQuery(new(testRow), db).Transaction(func(query *Query[*testRow]) error {
+            res = query.Insert(&testRow{Name: "a"}, &testRow{Name: "b"})
+            return res.Err
+        })
+        done <- res
+    }()
+
+    select {
+    case res := <-done:
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        if want := []int64{11, 13}; reflect.DeepEqual(res.InsertIds, want) == false {
+            t.Errorf("want ids %v, got %v", want, res.InsertIds)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("want auto_increment_increment read through transaction, blocked")
+    }
+}
+
+func TestVersionSupportRowAlias(t *testing.T) {
+    tests := []struct {
+        version string
+        want    bool
+    }{
+        {version: "5.7.40", want: false},
+        {version: "5.7.40-log", want: false},
+        {version: "8.0.18", want: false},
+        {version: "8.0.19", want: true},
+        {version: "8.0.36-0ubuntu0.22.04.1", want: true},
+        {version: "8.1.0", want: true},
+        {version: "9.0.1", want: true},
+        {version: "10.6.12-MariaDB", want: false},
+        {version: "", want: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.version, func(t *testing.T) {
+            if got := versionSupportRowAlias(tt.version); got != tt.want {
+                t.Errorf("want %v, got %v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestUpsertRowAlias(t *testing.T) {
+    upsert := func(version string, useRowAlias *bool) string {
+        db, fake := newFakeDB()
+        fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+            return fakeValueRows("@@version", version), nil
+        }
+        query := NewQuery(new(testRow), db)
+        if useRowAlias != nil {
+            query.UseRowAlias(*useRowAlias)
+        }
+        res := query.OnConflictUpdate(&query.T.Name, &query.T.Name,
+            &query.T.Data, Raw("concat(`data`, ")+query.InsertedValue(&query.T.Data)+")").
+            Insert(&testRow{Id: 1, Name: "a", Data: "x"})
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        return res.PrepareSql
+    }
+
+    want := "insert ignore into mydb.test_row (`id`,`name`,`data`) values (?,?,?) as new on duplicate key update " +
+        "mydb.test_row.`name` = new.`name`,mydb.test_row.`data` = concat(`data`, new.`data`);"
+    if got := upsert("8.0.36", nil); got != want {
+        t.Errorf("8.0.36\nwant: %s\ngot:  %s", want, got)
+    }
+
+    want = "insert ignore into mydb.test_row (`id`,`name`,`data`) values (?,?,?) on duplicate key update " +
+        "mydb.test_row.`name` = values(`name`),mydb.test_row.`data` = concat(`data`, values(`data`));"
+    if got := upsert("5.7.40", nil); got != want {
+        t.Errorf("5.7.40\nwant: %s\ngot:  %s", want, got)
+    }
+    useRowAlias := false
+    if got := upsert("8.0.36", &useRowAlias); got != want {
+        t.Errorf("UseRowAlias(false)\nwant: %s\ngot:  %s", want, got)
+    }
+}
+
+func TestInsertedValueInTransaction(t *testing.T) {
+    db, fake := newFakeDB()
+    //connection of transaction is the only one
+    db.SetMaxOpenConns(1)
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows("@@version", "8.0.36"), nil
+    }
+
+    done := make(chan QueryResult, 1)
+    go func() {
+        var res QueryResult
+        NewQuery(new(testRow), db).Transaction(func(query *Query[*testRow]) error {
+            res = query.OnConflictUpdate(&query.T.Data, query.InsertedValue(&query.T.Data)).Insert(&testRow{Id: 1, Data: "x"})
+            return res.Err
+        })
+        done <- res
+    }()
+
+    select {
+    case res := <-done:
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        want := "insert ignore into mydb.test_row (`id`,`name`,`data`) values (?,?,?) as new on duplicate key update mydb.test_row.`data` = new.`data`;"
+        if res.PrepareSql != want {
+            t.Errorf("want: %s\ngot:  %s", want, res.PrepareSql)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("want version read through transaction, blocked")
+    }
+}
+
+func TestInsertedValueOfSubQuery(t *testing.T) {
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows("@@version", "8.0.36"), nil
+    }
+    table := new(testRow)
+    sub := NewQuery(table, db).Select(&table.Id, &table.Name).Where(&table.Id, 1).SubQuery()
+
+    query := NewQuery(table, db).Select(&table.Id, &table.Name)
+    res := query.OnConflictUpdate(&query.T.Name, query.InsertedValue(&query.T.Name)).InsertSubquery(sub)
+    if res.Err != ErrRowAliasOfSubQuery {
+        t.Errorf("want ErrRowAliasOfSubQuery, got %v", res.Err)
+    }
+
+    //same decision for InsertedValue and insert
+    query = NewQuery(table, db).Select(&table.Id, &table.Name).UseRowAlias(false)
+    res = query.OnConflictUpdate(&query.T.Name, query.InsertedValue(&query.T.Name)).InsertSubquery(sub)
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    if want := "on duplicate key update mydb.test_row.`name` = values(`name`);"; strings.HasSuffix(res.PrepareSql, want) == false {
+        t.Errorf("want suffix %s, got %s", want, res.PrepareSql)
+    }
+    for _, v := range fake.recorded() {
+        if strings.HasPrefix(v.sql, "insert") && strings.Contains(v.sql, " as new") {
+            t.Errorf("want no row alias, got %s", v.sql)
+        }
+    }
+}
+
+func TestInsertModeRowCounts(t *testing.T) {
+    rows := func() []*testRow {
+        return []*testRow{{Id: 1, Name: "a"}, {Id: 2, Name: "b"}, {Id: 3, Name: "c"}}
+    }
+
+    tests := []struct {
+        name         string
+        rowsAffected int64
+        run          func(query *Query[*testRow]) QueryResult
+        prefix       string
+        want         [3]int64 //inserted, ignored, replaced
+    }{
+        {
+            name:         "insert",
+            rowsAffected: 3,
+            run:          func(query *Query[*testRow]) QueryResult { return query.Insert(rows()...) },
+            prefix:       "insert into",
+            want:         [3]int64{3, 0, 0},
+        },
+        {
+            name:         "insert ignore",
+            rowsAffected: 1,
+            run:          func(query *Query[*testRow]) QueryResult { return query.InsertIgnore(rows()...) },
+            prefix:       "insert ignore into",
+            want:         [3]int64{1, 2, 0},
+        },
+        {
+            name:         "replace",
+            rowsAffected: 5,
+            run:          func(query *Query[*testRow]) QueryResult { return query.Replace(rows()...) },
+            prefix:       "replace into",
+            want:         [3]int64{1, 0, 2},
+        },
+        {
+            name:         "replace new rows",
+            rowsAffected: 3,
+            run:          func(query *Query[*testRow]) QueryResult { return query.Replace(rows()...) },
+            prefix:       "replace into",
+            want:         [3]int64{3, 0, 0},
+        },
+        {
+            name:         "insert ignore subquery",
+            rowsAffected: 4,
+            run: func(query *Query[*testRow]) QueryResult {
+                return query.InsertIgnoreSubquery(NewQuery(new(testRow)).Select(&query.T.Id, &query.T.Name, &query.T.Data).SubQuery())
+            },
+            prefix: "insert ignore into",
+            want:   [3]int64{4, 0, 0},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            db, fake := newFakeDB()
+            fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+                return fakeResult{rowsAffected: tt.rowsAffected}, nil
+            }
+
+            res := tt.run(NewQuery(new(testRow), db))
+            if res.Err != nil {
+                t.Fatal(res.Err)
+            }
+            if strings.HasPrefix(res.PrepareSql, tt.prefix+" ") == false {
+                t.Errorf("want %q prefix, got %s", tt.prefix, res.PrepareSql)
+            }
+            if got := [3]int64{res.RowsInserted, res.RowsIgnored, res.RowsReplaced}; got != tt.want {
+                t.Errorf("want inserted, ignored, replaced %v, got %v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestReplaceWithConflictUpdate(t *testing.T) {
+    db, fake := newFakeDB()
+    query := NewQuery(new(testRow), db).UseRowAlias(false)
+    res := query.OnConflictUpdate(&query.T.Name, &query.T.Name).Replace(&testRow{Id: 1, Name: "a"})
+    if res.Err != ErrReplaceWithConflictUpdate {
+        t.Errorf("want ErrReplaceWithConflictUpdate, got %v", res.Err)
+    }
+    if len(fake.recorded()) > 0 {
+        t.Errorf("want no statement, got %v", fake.recorded())
+    }
+}
@@ -45,6 +45,8 @@ func (q *Query[T]) join(joinType JoinType, table Table, wheref func(where *Query
         newTable.alias = alias[0]
     } else if newTable.rawSql != "" {
         newTable.alias = subqueryDefaultName
+    } else if _, ok := table.(ShardTable); ok {
+        newTable.alias = table.TableName()
     }
 
     newTable.joinType = joinType
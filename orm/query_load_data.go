@@ -0,0 +1,269 @@
+package orm
+
+import (
+    "bufio"
+    "database/sql/driver"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "github.com/go-sql-driver/mysql"
+    "io"
+    "reflect"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+var loadDataHandlerId int64
+
+//load rows by "load data local infile", much faster than insert for huge data
+//columns can be limited by Select, server variable local_infile should be ON
+func (q *Query[T]) LoadData(rows []T) QueryResult {
+    if len(rows) == 0 {
+        q.setErr(errors.New("slice is empty"))
+        return q.result
+    }
+
+    val := reflect.ValueOf(rows)
+    if val.Index(0).IsNil() {
+        q.setErr(errors.New("slice elem must not be nil"))
+        return q.result
+    }
+    if _, ok := q.tables[0].table.(ShardTable); ok && q.tables[0].shard == nil {
+        return q.loadDataInShards(rows)
+    }
+    fieldNames, err := getStructFieldNameSlice(val.Index(0).Elem().Interface())
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+    structDefaults, err := getStructFieldWithDefaultTime(val.Index(0).Elem().Interface())
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+
+    var fieldIndexes []int
+    var columns []string
+    if len(q.columns) > 0 {
+        fieldIndexes, err = q.getBulkUpdateFields(q.columns)
+        if err != nil {
+            q.setErr(err)
+            return q.result
+        }
+    } else {
+        for k, v := range fieldNames {
+            if v != "" {
+                fieldIndexes = append(fieldIndexes, k)
+            }
+        }
+    }
+    for _, v := range fieldIndexes {
+        columns = append(columns, fieldNames[v])
+    }
+
+    //time converted like driver does
+    loc := dbLocation(q.DB())
+    reader, writer := io.Pipe()
+    go func() {
+        w := bufio.NewWriter(writer)
+        var err error
+        for i := 0; i < val.Len() && err == nil; i++ {
+            row := val.Index(i)
+            if row.IsNil() {
+                err = errors.New("slice elem must not be nil")
+                break
+            }
+            for k, index := range fieldIndexes {
+                if k > 0 {
+                    _ = w.WriteByte(',')
+                }
+                field := row.Elem().Field(index)
+                if structDefaults[index] != nil && field.IsZero() {
+                    err = writeLoadDataValue(w, structDefaults[index], loc)
+                } else {
+                    err = writeLoadDataValue(w, field.Interface(), loc)
+                }
+                if err != nil {
+                    break
+                }
+            }
+            _ = w.WriteByte('\n')
+        }
+        if err == nil {
+            err = w.Flush()
+        }
+        _ = writer.CloseWithError(err)
+    }()
+    defer func() {
+        _ = reader.Close()
+    }()
+
+    return q.loadData(reader, columns, "\n")
+}
+
+//load rows into their shards, by shard key of each row
+func (q *Query[T]) loadDataInShards(rows []T) QueryResult {
+    shards, groups, err := q.groupRowsByShard(rows)
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+
+    var result QueryResult
+    for _, shard := range shards {
+        nq := q.Clone()
+        nq.useShard(shard)
+        res := nq.LoadData(groups[shard.id()])
+
+        mergeInsertResult(&result, res)
+        if res.Err != nil {
+            break
+        }
+    }
+
+    q.result = result
+    return q.result
+}
+
+//load csv with header line, columnMapping: csv header => ptr of T.field or column name
+//all header names should be column names if columnMapping is nil, else columns not mapped are skipped
+func (q *Query[T]) LoadCSV(reader io.Reader, columnMapping map[string]any) QueryResult {
+    bufReader := bufio.NewReader(reader)
+    headerLine, err := bufReader.ReadString('\n')
+    if err != nil && (err != io.EOF || headerLine == "") {
+        q.setErr(err)
+        return q.result
+    }
+
+    lineTerminator := "\n"
+    if strings.HasSuffix(headerLine, "\r\n") {
+        lineTerminator = "\r\n"
+    }
+
+    headers, err := csv.NewReader(strings.NewReader(headerLine)).Read()
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+
+    columns := make([]string, len(headers))
+    for k, v := range headers {
+        if columnMapping == nil {
+            if _, ok := q.getOrmFieldByName(v); ok == false {
+                q.setErr(fmt.Errorf("%w: %s", ErrColumnNotExisted, v))
+                return q.result
+            }
+            columns[k] = v
+            continue
+        }
+        target, ok := columnMapping[v]
+        if ok == false || target == nil {
+            continue
+        }
+        column, err := q.parseColumn(target)
+        if err != nil {
+            q.setErr(err)
+            return q.result
+        }
+        columns[k] = columnName(column)
+    }
+
+    return q.loadData(bufReader, columns, lineTerminator)
+}
+
+func (q *Query[T]) getOrmFieldByName(name string) (any, bool) {
+    for k, v := range q.tables[0].ormFields {
+        if v == name {
+            return k, true
+        }
+    }
+    return nil, false
+}
+
+//columns: empty column is skipped
+func (q *Query[T]) loadData(reader io.Reader, columns []string, lineTerminator string) QueryResult {
+    name := "orm_load_data_" + strconv.FormatInt(atomic.AddInt64(&loadDataHandlerId, 1), 10)
+    mysql.RegisterReaderHandler(name, func() io.Reader {
+        return reader
+    })
+    defer mysql.DeregisterReaderHandler(name)
+
+    columnStrs := make([]string, len(columns))
+    for k, v := range columns {
+        if v == "" {
+            columnStrs[k] = "@skip"
+        } else {
+            columnStrs[k] = "`" + v + "`"
+        }
+    }
+
+    q.requireShard()
+    if q.result.Err != nil {
+        return q.result
+    }
+
+    rawSql := "load data local infile 'Reader::" + name + "' into table " + q.tables[0].getTableName()
+    rawSql += " character set utf8mb4 fields terminated by ',' optionally enclosed by '\"' escaped by ''"
+    rawSql += " lines terminated by '" + strings.ReplaceAll(strings.ReplaceAll(lineTerminator, "\r", "\\r"), "\n", "\\n") + "'"
+    rawSql += " (" + strings.Join(columnStrs, ",") + ")"
+
+    q.prepareSql = rawSql
+    q.bindings = nil
+    q.kind = QueryKindInsert
+
+    return q.Execute()
+}
+
+//csv value: NULL for nil, others enclosed by '"', time in loc of connection
+func writeLoadDataValue(w *bufio.Writer, v any, loc *time.Location) error {
+    switch val := v.(type) {
+    case nil:
+        _, err := w.WriteString(nullStr)
+        return err
+    case string:
+        return writeLoadDataString(w, val)
+    case []byte:
+        return writeLoadDataString(w, string(val))
+    case bool:
+        if val {
+            return writeLoadDataString(w, "1")
+        }
+        return writeLoadDataString(w, "0")
+    case time.Time:
+        if val.IsZero() {
+            return writeLoadDataString(w, "0000-00-00 00:00:00")
+        }
+        return writeLoadDataString(w, val.In(loc).Format("2006-01-02 15:04:05.999999"))
+    case float32:
+        return writeLoadDataString(w, strconv.FormatFloat(float64(val), 'f', -1, 32))
+    case float64:
+        return writeLoadDataString(w, strconv.FormatFloat(val, 'f', -1, 64))
+    case driver.Valuer:
+        rv := reflect.ValueOf(val)
+        if rv.Kind() == reflect.Ptr && rv.IsNil() {
+            return writeLoadDataValue(w, nil, loc)
+        }
+        temp, err := val.Value()
+        if err != nil {
+            return err
+        }
+        return writeLoadDataValue(w, temp, loc)
+    default:
+        rv := reflect.ValueOf(v)
+        if rv.Kind() == reflect.Ptr {
+            if rv.IsNil() {
+                return writeLoadDataValue(w, nil, loc)
+            }
+            return writeLoadDataValue(w, rv.Elem().Interface(), loc)
+        }
+        return writeLoadDataString(w, fmt.Sprint(v))
+    }
+}
+
+func writeLoadDataString(w *bufio.Writer, s string) error {
+    _ = w.WriteByte('"')
+    _, _ = w.WriteString(strings.ReplaceAll(s, `"`, `""`))
+    return w.WriteByte('"')
+}
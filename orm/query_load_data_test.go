@@ -0,0 +1,93 @@
+package orm
+
+import (
+    "bufio"
+    "database/sql"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWriteLoadDataValue(t *testing.T) {
+    shanghai := time.FixedZone("Asia/Shanghai", 8*3600)
+    name := "john"
+    var nilName *string
+
+    tests := []struct {
+        name string
+        v    any
+        loc  *time.Location
+        want string
+    }{
+        {name: "nil", v: nil, loc: time.UTC, want: `NULL`},
+        {name: "string", v: `say "hi"`, loc: time.UTC, want: `"say ""hi"""`},
+        {name: "bytes", v: []byte("abc"), loc: time.UTC, want: `"abc"`},
+        {name: "bool", v: true, loc: time.UTC, want: `"1"`},
+        {name: "int", v: 42, loc: time.UTC, want: `"42"`},
+        {name: "float", v: 1.5, loc: time.UTC, want: `"1.5"`},
+        {name: "ptr", v: &name, loc: time.UTC, want: `"john"`},
+        {name: "nil ptr", v: nilName, loc: time.UTC, want: `NULL`},
+        {name: "valuer", v: sql.NullInt64{Int64: 3, Valid: true}, loc: time.UTC, want: `"3"`},
+        {name: "null valuer", v: sql.NullString{}, loc: time.UTC, want: `NULL`},
+        {name: "zero time", v: time.Time{}, loc: time.UTC, want: `"0000-00-00 00:00:00"`},
+        {name: "time utc", v: time.Date(2024, 1, 2, 3, 4, 5, 0, shanghai), loc: time.UTC, want: `"2024-01-01 19:04:05"`},
+        {name: "time in loc", v: time.Date(2024, 1, 1, 19, 4, 5, 500000000, time.UTC), loc: shanghai, want: `"2024-01-02 03:04:05.5"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var str strings.Builder
+            w := bufio.NewWriter(&str)
+            if err := writeLoadDataValue(w, tt.v, tt.loc); err != nil {
+                t.Fatal(err)
+            }
+            _ = w.Flush()
+            if str.String() != tt.want {
+                t.Errorf("want %s, got %s", tt.want, str.String())
+            }
+        })
+    }
+}
+
+func TestLoadCSV(t *testing.T) {
+    db, fake := newFakeDB()
+    query := NewQuery(new(testRow), db)
+    res := query.LoadCSV(strings.NewReader("user_name,age\r\njohn,20\r\n"), map[string]any{"user_name": &query.T.Name})
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+    statements := fake.recorded()
+    if len(statements) != 1 {
+        t.Fatalf("want 1 statement, got %v", statements)
+    }
+    if want := " into table mydb.test_row character set utf8mb4 fields terminated by ',' optionally enclosed by '\"' escaped by ''" +
+        " lines terminated by '\\r\\n' (`name`,@skip)"; strings.HasSuffix(statements[0].sql, want) == false {
+        t.Errorf("want suffix %s, got %s", want, statements[0].sql)
+    }
+
+    res = NewQuery(new(testRow), db).LoadCSV(strings.NewReader("name,age\n"), nil)
+    if errors.Is(res.Err, ErrColumnNotExisted) == false {
+        t.Errorf("want ErrColumnNotExisted of header age, got %v", res.Err)
+    }
+}
+
+func TestDBLocation(t *testing.T) {
+    db, err := OpenMysql("user:pass@tcp(127.0.0.1:3306)/mydb?loc=Local")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := dbLocation(db); got != time.Local {
+        t.Errorf("want Local, got %s", got)
+    }
+
+    db, _ = OpenMysql("user:pass@tcp(127.0.0.1:3306)/mydb")
+    if got := dbLocation(db); got != time.UTC {
+        t.Errorf("want UTC, got %s", got)
+    }
+
+    SetDBLocation(db, time.Local)
+    if got := dbLocation(db); got != time.Local {
+        t.Errorf("want Local by SetDBLocation, got %s", got)
+    }
+}
@@ -8,8 +8,14 @@ type QueryResult struct {
     PrepareSql   string
     Bindings     []any
     LastInsertId int64
+    InsertIds    []int64 //ids of inserted rows, filled by Insert
     RowsAffected int64
+    RowsInserted int64 //rows actually inserted by Insert, InsertIgnore, Replace
+    RowsIgnored  int64 //rows ignored by InsertIgnore
+    RowsReplaced int64 //rows replaced by Replace
     Err          error
+
+    rowsExamined int64 //for query stats
 }
 
 func (q QueryResult) Error() error {
@@ -15,6 +15,7 @@ func (q *Query[T]) SubQuery() *SubQuery {
         tempTable.dbs = mt.DBs()
         tempTable.tx = mt.tx
         tempTable.dbName = mt.tables[0].table.DatabaseName()
+        tempTable.tables = mt.readTables()
         if mt.result.Err != nil {
             tempTable.err = mt.result.Err
         }
@@ -27,6 +28,7 @@ func (q *Query[T]) SubQuery() *SubQuery {
         tempTable.dbs = mt.DBs()
         tempTable.tx = mt.tx
         tempTable.dbName = mt.tables[0].table.DatabaseName()
+        tempTable.tables = mt.readTables()
         if mt.result.Err != nil {
             tempTable.err = mt.result.Err
         }
@@ -37,6 +39,7 @@ func (q *Query[T]) SubQuery() *SubQuery {
 
 func (q *Query[T]) generateSelectQuery(columns ...any) *SubQuery {
     var ret SubQuery
+    q.resolveShard()
     if q.prepareSql != "" {
         ret.raw = q.prepareSql
         ret.bindings = q.bindings
@@ -87,6 +90,10 @@ func (q *Query[T]) generateSelectQuery(columns ...any) *SubQuery {
 
         orderLimitOffsetStr := q.getOrderAndLimitSqlStr()
 
+        if err := q.checkPendingColumns(); err != nil {
+            ret.err = err
+        }
+
         var selectKeyword = "select"
         if q.selectTimeout != "" {
             selectKeyword += " " + q.selectTimeout
@@ -0,0 +1,233 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "sort"
+    "sync"
+    "time"
+)
+
+//latest durations kept per fingerprint for p99
+const queryStatsSamples = 1024
+
+var queryStatsEnabled bool
+var queryStats = &queryStatsRegistry{stats: make(map[string]*queryStatsEntry)}
+
+//statistics of a query shape
+type QueryStats struct {
+    Fingerprint   string
+    Table         string
+    Count         int64
+    Errors        int64
+    Rows          int64 //rows returned or affected
+    RowsExamined  int64 //rows read by storage engine, Handler_read_* of session status
+    TotalDuration time.Duration
+    AvgDuration   time.Duration
+    P99Duration   time.Duration //of latest 1024 executions
+}
+
+type queryStatsEntry struct {
+    stats   QueryStats
+    samples []time.Duration
+    next    int
+}
+
+type queryStatsRegistry struct {
+    mu    sync.Mutex
+    stats map[string]*queryStatsEntry
+}
+
+//collect statistics per fingerprint of statements
+//rows examined read by "show session status" before and after each statement, on its connection
+func EnableQueryStats(enable bool) {
+    queryStatsEnabled = enable
+}
+
+func ResetQueryStats() {
+    queryStats.mu.Lock()
+    defer queryStats.mu.Unlock()
+    queryStats.stats = make(map[string]*queryStatsEntry)
+}
+
+func recordQueryStats(info QueryInfo, res QueryResult, err error, duration time.Duration) {
+    if queryStatsEnabled == false {
+        return
+    }
+    fingerprint := Fingerprint(info.Sql)
+
+    queryStats.mu.Lock()
+    defer queryStats.mu.Unlock()
+
+    entry := queryStats.stats[fingerprint]
+    if entry == nil {
+        entry = &queryStatsEntry{stats: QueryStats{Fingerprint: fingerprint, Table: info.Table}}
+        queryStats.stats[fingerprint] = entry
+    }
+    entry.stats.Count++
+    entry.stats.Rows += res.RowsAffected
+    entry.stats.RowsExamined += res.rowsExamined
+    entry.stats.TotalDuration += duration
+    if err != nil {
+        entry.stats.Errors++
+    }
+    if len(entry.samples) < queryStatsSamples {
+        entry.samples = append(entry.samples, duration)
+    } else {
+        entry.samples[entry.next] = duration
+        entry.next = (entry.next + 1) % queryStatsSamples
+    }
+}
+
+//tx, db, or connection of db a statement runs on
+type queryRunner interface {
+    ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+//run statement through tx if in transaction, or db
+//one connection of db if query stats enabled, rows examined counted on it
+func (q *Query[T]) runStatement(ctx context.Context, db *sql.DB, run func(runner queryRunner) (QueryResult, error)) (QueryResult, error) {
+    var runner queryRunner = db
+    if q.Tx() != nil {
+        runner = q.Tx()
+    }
+    if queryStatsEnabled == false {
+        return run(runner)
+    }
+
+    if q.Tx() == nil {
+        conn, err := db.Conn(ctx)
+        if err != nil {
+            return q.result, err
+        }
+        defer conn.Close()
+        runner = conn
+    }
+    before := sessionRowsRead(ctx, runner)
+    res, err := run(runner)
+    if err == nil && before >= 0 {
+        if after := sessionRowsRead(ctx, runner); after >= before {
+            res.rowsExamined = after - before
+        }
+    }
+    return res, err
+}
+
+//sum of Handler_read_* of session, -1 if unknown
+func sessionRowsRead(ctx context.Context, runner queryRunner) int64 {
+    rows, err := runner.QueryContext(ctx, "show session status like 'Handler_read%'")
+    if err != nil {
+        return -1
+    }
+    defer rows.Close()
+
+    var total int64
+    for rows.Next() {
+        var name string
+        var value int64
+        if err = rows.Scan(&name, &value); err != nil {
+            return -1
+        }
+        total += value
+    }
+    if rows.Err() != nil {
+        return -1
+    }
+    return total
+}
+
+//top n query shapes by total duration, all if n <= 0
+func TopQueries(n int) []QueryStats {
+    queryStats.mu.Lock()
+    ret := make([]QueryStats, 0, len(queryStats.stats))
+    samples := make([][]time.Duration, 0, len(queryStats.stats))
+    for _, v := range queryStats.stats {
+        ret = append(ret, v.stats)
+        samples = append(samples, append([]time.Duration{}, v.samples...))
+    }
+    queryStats.mu.Unlock()
+
+    for k := range ret {
+        ret[k].AvgDuration = ret[k].TotalDuration / time.Duration(ret[k].Count)
+        ret[k].P99Duration = percentileDuration(samples[k], 0.99)
+    }
+    sort.Slice(ret, func(i, j int) bool {
+        if ret[i].TotalDuration != ret[j].TotalDuration {
+            return ret[i].TotalDuration > ret[j].TotalDuration
+        }
+        return ret[i].Fingerprint < ret[j].Fingerprint
+    })
+    if n > 0 && n < len(ret) {
+        ret = ret[:n]
+    }
+    return ret
+}
+
+func percentileDuration(samples []time.Duration, p float64) time.Duration {
+    if len(samples) == 0 {
+        return 0
+    }
+    sort.Slice(samples, func(i, j int) bool {
+        return samples[i] < samples[j]
+    })
+    index := int(float64(len(samples))*p+0.5) - 1
+    if index < 0 {
+        index = 0
+    } else if index >= len(samples) {
+        index = len(samples) - 1
+    }
+    return samples[index]
+}
+
+//pass TopQueries(n) to dump every interval, logged at info level if dump is nil
+//call returned stop func to stop dumping, error if interval is not positive
+func DumpTopQueries(interval time.Duration, n int, dump func(stats []QueryStats)) (stop func(), err error) {
+    if interval <= 0 {
+        return nil, errors.New("interval of DumpTopQueries must be positive")
+    }
+    if dump == nil {
+        dump = logTopQueries
+    }
+    done := make(chan struct{})
+    var once sync.Once
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-done:
+                return
+            case <-ticker.C:
+                dump(TopQueries(n))
+            }
+        }
+    }()
+    return func() {
+        once.Do(func() {
+            close(done)
+        })
+    }, nil
+}
+
+func logTopQueries(stats []QueryStats) {
+    for k, v := range stats {
+        if structuredLogger != nil {
+            structuredLogger.InfoContext(context.Background(), "orm top query",
+                "rank", k+1,
+                "fingerprint", v.Fingerprint,
+                "table", v.Table,
+                "count", v.Count,
+                "errors", v.Errors,
+                "rows", v.Rows,
+                "rows_examined", v.RowsExamined,
+                "total", v.TotalDuration,
+                "avg", v.AvgDuration,
+                "p99", v.P99Duration,
+            )
+        } else if infoLogger != nil {
+            infoLogger.Info("top query", k+1, v.Fingerprint, v.Count, v.TotalDuration.String(), v.AvgDuration.String(), v.P99Duration.String())
+        }
+    }
+}
@@ -0,0 +1,219 @@
+package orm
+
+import (
+    "database/sql/driver"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+//query stats during test, empty at start
+func withQueryStats(t *testing.T) {
+    ResetQueryStats()
+    EnableQueryStats(true)
+    t.Cleanup(func() {
+        EnableQueryStats(false)
+        ResetQueryStats()
+    })
+}
+
+func TestQueryStats(t *testing.T) {
+    withQueryStats(t)
+
+    db, fake := newFakeDB()
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        if args[len(args)-1] == 3 {
+            return nil, errors.New("deadlock")
+        }
+        return fakeResult{rowsAffected: 1}, nil
+    }
+    table := new(testRow)
+
+    for _, id := range []int{1, 2, 3} {
+        NewQuery(table, db).Where(&table.Id, id).Update(&table.Name, "john")
+    }
+    NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2}).Gets()
+    NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2, 3}).Gets()
+
+    stats := TopQueries(0)
+    if len(stats) != 2 {
+        t.Fatalf("want 2 query shapes, got %+v", stats)
+    }
+
+    byFingerprint := make(map[string]QueryStats)
+    for _, v := range stats {
+        byFingerprint[v.Fingerprint] = v
+    }
+    update := byFingerprint["update mydb.test_row set mydb.test_row.`name` = ? where mydb.test_row.`id` = ?"]
+    if update.Count != 3 || update.Errors != 1 || update.Rows != 2 || update.Table != "mydb.test_row" {
+        t.Errorf("want 3 updates, 1 failed, 2 rows, got %+v", update)
+    }
+    if update.AvgDuration != update.TotalDuration/3 || update.P99Duration > update.TotalDuration {
+        t.Errorf("want avg and p99 of total, got %+v", update)
+    }
+    selects := byFingerprint["select * from mydb.test_row where mydb.test_row.`id` in (?+)"]
+    if selects.Count != 2 {
+        t.Errorf("want where in of any length as one shape, got %+v", stats)
+    }
+}
+
+func TestQueryStatsRowsExamined(t *testing.T) {
+    withQueryStats(t)
+
+    db, fake := newFakeDB()
+    //connection of statement is the only one
+    db.SetMaxOpenConns(1)
+    var handlerRead int64
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        if strings.HasPrefix(sqlStr, "show session status") {
+            return &fakeRows{
+                columns: []string{"Variable_name", "Value"},
+                rows:    [][]driver.Value{{"Handler_read_key", handlerRead}, {"Handler_read_next", int64(1)}},
+            }, nil
+        }
+        handlerRead += 5
+        return &fakeRows{}, nil
+    }
+    table := new(testRow)
+
+    NewQuery(table, db).Where(&table.Name, "a").Gets()
+    NewQuery(table, db).Where(&table.Name, "b").Gets()
+    NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        _, res := query.Where(&table.Name, "c").Gets()
+        return res.Err
+    })
+
+    stats := TopQueries(0)
+    if len(stats) != 1 || stats[0].Count != 3 || stats[0].RowsExamined != 15 {
+        t.Errorf("want 15 rows examined of 3 selects, got %+v", stats)
+    }
+
+    var sqls []string
+    for _, v := range fake.recorded() {
+        sqls = append(sqls, strings.SplitN(v.sql, " ", 2)[0])
+    }
+    if want := "show select show show select show show select show"; strings.Join(sqls, " ") != want {
+        t.Errorf("want session status before and after each statement, got %v", sqls)
+    }
+
+    EnableQueryStats(false)
+    before := len(fake.recorded())
+    NewQuery(table, db).Where(&table.Name, "a").Gets()
+    if got := fake.recorded()[before:]; len(got) != 1 {
+        t.Errorf("want no session status if query stats disabled, got %v", got)
+    }
+}
+
+func TestTopQueries(t *testing.T) {
+    withQueryStats(t)
+
+    info := QueryInfo{Kind: QueryKindSelect, Table: "user"}
+    for _, v := range []struct {
+        sql      string
+        duration time.Duration
+    }{
+        {"select * from user where id = 1", 10 * time.Millisecond},
+        {"select * from user where id = 2", 30 * time.Millisecond},
+        {"select * from user where name = 'john'", 50 * time.Millisecond},
+        {"select * from user limit 10", 5 * time.Millisecond},
+    } {
+        info.Sql = v.sql
+        recordQueryStats(info, QueryResult{}, nil, v.duration)
+    }
+
+    top := TopQueries(2)
+    if len(top) != 2 {
+        t.Fatalf("want 2, got %d", len(top))
+    }
+    if top[0].Fingerprint != "select * from user where name = ?" || top[0].TotalDuration != 50*time.Millisecond {
+        t.Errorf("want slowest by total first, got %+v", top[0])
+    }
+    if top[1].Fingerprint != "select * from user where id = ?" || top[1].Count != 2 || top[1].AvgDuration != 20*time.Millisecond {
+        t.Errorf("want id shape second, got %+v", top[1])
+    }
+    if len(TopQueries(0)) != 3 {
+        t.Errorf("want all shapes, got %d", len(TopQueries(0)))
+    }
+
+    ResetQueryStats()
+    if len(TopQueries(0)) != 0 {
+        t.Errorf("want empty after reset, got %+v", TopQueries(0))
+    }
+}
+
+func TestQueryStatsDisabled(t *testing.T) {
+    ResetQueryStats()
+    recordQueryStats(QueryInfo{Sql: "select 1"}, QueryResult{}, nil, time.Millisecond)
+    if len(TopQueries(0)) != 0 {
+        t.Errorf("want nothing recorded when disabled, got %+v", TopQueries(0))
+    }
+}
+
+func TestPercentileDuration(t *testing.T) {
+    samples := make([]time.Duration, 0, 200)
+    for i := 200; i > 0; i-- {
+        samples = append(samples, time.Duration(i)*time.Millisecond)
+    }
+    tests := []struct {
+        samples []time.Duration
+        p       float64
+        want    time.Duration
+    }{
+        {samples: nil, p: 0.99, want: 0},
+        {samples: []time.Duration{time.Second}, p: 0.99, want: time.Second},
+        {samples: samples, p: 0.99, want: 198 * time.Millisecond},
+        {samples: samples, p: 0.5, want: 100 * time.Millisecond},
+        {samples: samples, p: 0, want: time.Millisecond},
+    }
+
+    for _, tt := range tests {
+        if got := percentileDuration(tt.samples, tt.p); got != tt.want {
+            t.Errorf("p%v of %d samples: want %v, got %v", tt.p, len(tt.samples), tt.want, got)
+        }
+    }
+}
+
+func TestDumpTopQueries(t *testing.T) {
+    withQueryStats(t)
+    recordQueryStats(QueryInfo{Sql: "select * from user where id = 1"}, QueryResult{}, nil, time.Millisecond)
+
+    dumped := make(chan []QueryStats, 1)
+    stop, err := DumpTopQueries(time.Millisecond, 1, func(stats []QueryStats) {
+        select {
+        case dumped <- stats:
+        default:
+        }
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer stop()
+
+    select {
+    case stats := <-dumped:
+        if len(stats) != 1 || stats[0].Fingerprint != "select * from user where id = ?" {
+            t.Errorf("want top query dumped, got %+v", stats)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("want dump within interval")
+    }
+    stop()
+    stop()
+
+    if _, err := DumpTopQueries(0, 1, nil); err == nil {
+        t.Error("want error of interval 0")
+    }
+}
+
+func TestStatementSpanFingerprint(t *testing.T) {
+    tr := withTracer(t)
+
+    db, _ := newFakeDB()
+    NewQuery(new(testRow), db).Raw("update mydb.test_row set name = 'john' where id in (1, 2)").Execute()
+
+    spans := tr.Spans()
+    if len(spans) != 1 || spans[0].Attributes["db.statement"] != "update mydb.test_row set name = ? where id in (?+)" {
+        t.Errorf("want fingerprint as db.statement, got %+v", spans)
+    }
+}
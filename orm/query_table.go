@@ -15,6 +15,7 @@ type queryTable struct {
     alias           string
     rawSql          string
     bindings        []any
+    shard           *Shard //shard of ShardTable routed to
 }
 
 func (q queryTable) getAlias() string {
@@ -42,11 +43,15 @@ func (q queryTable) getTableNameAndAlias() string {
 }
 
 func (q queryTable) getTableName() string {
-    if q.table.TableName() != "" {
+    tableName := q.table.TableName()
+    if q.shard != nil && q.shard.TableName != "" {
+        tableName = q.shard.TableName
+    }
+    if tableName != "" {
         if q.table.DatabaseName() != "" {
-            return q.table.DatabaseName() + "." + q.table.TableName()
+            return q.table.DatabaseName() + "." + tableName
         } else {
-            return q.table.TableName()
+            return tableName
         }
     }
     return ""
@@ -0,0 +1,32 @@
+package orm
+
+//prepared sql and bindings of Get, nothing executed
+func (q *Query[T]) ToSelectSql() (string, []any, error) {
+    tempTable := q.Clone().SubQuery()
+    return q.withSqlComment(tempTable.raw), tempTable.bindings, tempTable.err
+}
+
+//prepared sql and bindings of Update, nothing executed
+func (q *Query[T]) ToUpdateSql(column any, val any, columnVars ...any) (string, []any, error) {
+    nq := q.Clone()
+    nq.dryRun = true
+    res := nq.Update(column, val, columnVars...)
+    return res.PrepareSql, res.Bindings, res.Err
+}
+
+//prepared sql and bindings of Delete, nothing executed
+func (q *Query[T]) ToDeleteSql() (string, []any, error) {
+    nq := q.Clone()
+    nq.dryRun = true
+    res := nq.delete()
+    return res.PrepareSql, res.Bindings, res.Err
+}
+
+//prepared sql and bindings of Insert in one statement, nothing executed
+//on duplicate key update by values(col), unless UseRowAlias(true)
+func (q *Query[T]) ToInsertSql(data ...T) (string, []any, error) {
+    nq := q.Clone()
+    nq.dryRun = true
+    res := nq.insert(data)
+    return res.PrepareSql, res.Bindings, res.Err
+}
@@ -0,0 +1,124 @@
+package orm
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestToSql(t *testing.T) {
+    db, fake := newFakeDB()
+    //row alias if version queried
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        return fakeValueRows("@@version", "8.0.36"), nil
+    }
+    table := new(testRow)
+
+    tests := []struct {
+        name     string
+        toSql    func() (string, []any, error)
+        sql      string
+        bindings []any
+    }{
+        {
+            name: "select",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).Where(&table.Id, WhereIn, []int{1, 2}).OrderByDesc(&table.Id).Limit(10).ToSelectSql()
+            },
+            sql:      "select * from mydb.test_row where mydb.test_row.`id` in (?,?) order by mydb.test_row.`id` desc limit 10",
+            bindings: []any{1, 2},
+        },
+        {
+            name: "update",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).Where(&table.Id, 1).ToUpdateSql(&table.Name, "john", &table.Data, "x")
+            },
+            sql:      "update mydb.test_row set mydb.test_row.`name` = ?,mydb.test_row.`data` = ? where mydb.test_row.`id` = ?",
+            bindings: []any{"john", "x", 1},
+        },
+        {
+            name: "delete",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).Where(&table.Name, "john").Limit(5).ToDeleteSql()
+            },
+            sql:      "delete from mydb.test_row where mydb.test_row.`name` = ? limit 5",
+            bindings: []any{"john"},
+        },
+        {
+            name: "insert",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).ToInsertSql(&testRow{Name: "john"}, &testRow{Name: "mary", Data: "x"})
+            },
+            sql:      "insert into mydb.test_row (`id`,`name`,`data`) values (?,?,?),(?,?,?);",
+            bindings: []any{0, "john", "", 0, "mary", "x"},
+        },
+        {
+            name: "upsert without server version",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).OnConflictUpdate(&table.Name, &table.Name).ToInsertSql(&testRow{Id: 1, Name: "john"})
+            },
+            sql:      "insert ignore into mydb.test_row (`id`,`name`,`data`) values (?,?,?) on duplicate key update mydb.test_row.`name` = values(`name`);",
+            bindings: []any{1, "john", ""},
+        },
+        {
+            name: "upsert",
+            toSql: func() (string, []any, error) {
+                return NewQuery(table, db).UseRowAlias(true).OnConflictUpdate(&table.Name, &table.Name).ToInsertSql(&testRow{Id: 1, Name: "john"})
+            },
+            sql:      "insert ignore into mydb.test_row (`id`,`name`,`data`) values (?,?,?) as new on duplicate key update mydb.test_row.`name` = new.`name`;",
+            bindings: []any{1, "john", ""},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            sqlStr, bindings, err := tt.toSql()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if strings.TrimSpace(sqlStr) != tt.sql {
+                t.Errorf("\nwant: %s\ngot:  %s", tt.sql, sqlStr)
+            }
+            if reflect.DeepEqual(bindings, tt.bindings) == false {
+                t.Errorf("want bindings %v, got %v", tt.bindings, bindings)
+            }
+        })
+    }
+
+    if got := fake.recorded(); len(got) > 0 {
+        t.Errorf("want nothing executed or queried, got %v", got)
+    }
+}
+
+func TestToSqlError(t *testing.T) {
+    table := new(testRow)
+    if _, _, err := NewQuery(table).Where(new(int), 1).ToSelectSql(); err == nil {
+        t.Error("want error of select")
+    }
+    if _, _, err := NewQuery(table).ToUpdateSql(&table.Name, "john"); err == nil {
+        t.Error("want error of update without where")
+    }
+    if _, _, err := NewQuery(table).ToInsertSql(); err == nil {
+        t.Error("want error of insert without rows")
+    }
+}
+
+func TestToSqlNotChangingQuery(t *testing.T) {
+    db, fake := newFakeDB()
+    table := new(testRow)
+
+    query := NewQuery(table, db).Where(&table.Id, 1)
+    updateSql, _, _ := query.ToUpdateSql(&table.Name, "john")
+    deleteSql, _, _ := query.ToDeleteSql()
+    res := query.Update(&table.Name, "john")
+
+    if res.Err != nil || res.PrepareSql != updateSql {
+        t.Errorf("want same update executed, got %s, %v", res.PrepareSql, res.Err)
+    }
+    if strings.HasPrefix(deleteSql, "delete") == false {
+        t.Errorf("want delete sql, got %s", deleteSql)
+    }
+    if len(fake.recorded()) != 1 {
+        t.Errorf("want only update executed, got %v", fake.recorded())
+    }
+}
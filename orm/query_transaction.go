@@ -10,12 +10,28 @@ func (q *Query[T]) Transaction(f func(query *Query[T]) error) error {
         return err
     }
     q.tx = tx
+    beginTxEvictions(tx)
+
+    ctx, span := startSpan(q.context(), "transaction", Attribute{Key: "db.system", Value: "mysql"})
+    if span != nil {
+        //statements of f parented from transaction span
+        parent := q.ctx
+        q.ctx = &ctx
+        defer func() {
+            q.ctx = parent
+        }()
+    }
 
     err = f(q)
 
     if err != nil {
         _ = tx.Rollback()
+        endTxEvictions(tx, false)
+        endSpan(span, err, Attribute{Key: "db.transaction", Value: "rollback"})
         return err
     }
-    return tx.Commit()
+    err = tx.Commit()
+    endTxEvictions(tx, err == nil)
+    endSpan(span, err, Attribute{Key: "db.transaction", Value: "commit"})
+    return err
 }
@@ -50,6 +50,7 @@ func (q *Query[T]) genUpdates(vals ...any) ([]updateColumn, error) {
 
 func (q *Query[T]) updates(updates ...updateColumn) QueryResult {
     bindings := make([]any, 0)
+    q.requireShard()
 
     if len(q.wheres) == 0 && len(q.tables) <= 1 && q.limit == 0 {
         q.setErr(ErrUpdateWithoutCondition)
@@ -63,6 +64,8 @@ func (q *Query[T]) updates(updates ...updateColumn) QueryResult {
 
     orderAndLimitStr := q.getOrderAndLimitSqlStr()
 
+    q.setErr(q.checkPendingColumns())
+
     rawSql := "update " + tableStr
     if updateStr != "" {
         rawSql += " set " + updateStr
@@ -75,8 +78,9 @@ func (q *Query[T]) updates(updates ...updateColumn) QueryResult {
         rawSql += " " + orderAndLimitStr
     }
 
-    q.prepareSql = rawSql
+    q.prepareSql = q.withSqlComment(rawSql)
     q.bindings = bindings
+    q.kind = QueryKindUpdate
 
     return q.Execute()
 }
@@ -94,10 +98,10 @@ func (q *Query[T]) generateUpdateStr(updates []updateColumn, bindings *[]any) st
         val, ok := q.isRaw(v.val)
         if ok {
             temp = column + " = " + val
+            *bindings = append(*bindings, v.bindings...)
         } else if reflect.ValueOf(v.val).Kind() == reflect.Ptr {
             if v.val == v.col {
-                dotIndex := strings.LastIndex(column, ".")
-                temp = column + " = values(`" + strings.Trim(column[dotIndex+1:], "`") + "`)"
+                temp = column + " = " + string(q.insertedColumn(column))
             } else {
                 targetColumn, err := q.parseColumn(v.val)
                 if err == nil {
@@ -0,0 +1,133 @@
+package orm
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+)
+
+const defaultBatchSize = 1000
+
+//update rows with different values, one statement per batch:
+//update t set col = (case id when ? then ? ... else col end) where id in (...)
+//columns default to all fields except primary
+func (q *Query[T]) BulkUpdate(rows []T, columns ...any) QueryResult {
+    if len(rows) == 0 {
+        q.setErr(errors.New("slice is empty"))
+        return q.result
+    }
+    if q.tables[0].tableStructType == nil {
+        q.setErr(errors.New("slice elem must be T"))
+        return q.result
+    }
+
+    fieldIndexes, err := q.getBulkUpdateFields(columns)
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+    if len(fieldIndexes) == 0 {
+        q.setErr(ErrColumnNotSelected)
+        return q.result
+    }
+
+    primaryColumn, err := q.parseColumn(q.tables[0].tableStruct.Field(0).Addr().Interface())
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+
+    var result QueryResult
+    size := q.getBatchSize()
+    for start := 0; start < len(rows); start += size {
+        end := start + size
+        if end > len(rows) {
+            end = len(rows)
+        }
+        chunk := rows[start:end]
+
+        ids := make([]any, len(chunk))
+        for k, v := range chunk {
+            rowVal := reflect.ValueOf(v)
+            if rowVal.IsNil() {
+                q.setErr(errors.New("slice elem must not be nil"))
+                return q.result
+            }
+            ids[k] = rowVal.Elem().Field(0).Interface()
+        }
+
+        updates := make([]updateColumn, 0, len(fieldIndexes))
+        for _, index := range fieldIndexes {
+            col := q.tables[0].tableStruct.Field(index).Addr().Interface()
+            column, err := q.parseColumn(col)
+            if err != nil {
+                q.setErr(err)
+                return q.result
+            }
+
+            var caseStr strings.Builder
+            caseBindings := make([]any, 0, len(chunk)*2)
+            caseStr.WriteString("(case " + primaryColumn)
+            for k, v := range chunk {
+                caseStr.WriteString(" when ? then ?")
+                caseBindings = append(caseBindings, ids[k], reflect.ValueOf(v).Elem().Field(index).Interface())
+            }
+            caseStr.WriteString(" else " + column + " end)")
+
+            updates = append(updates, updateColumn{col: col, val: Raw(caseStr.String()), bindings: caseBindings})
+        }
+
+        nq := q.Clone()
+        nq.wheres = append([]where{}, q.wheres...)
+        res := nq.WherePrimary(ids).updates(updates...)
+
+        result.PrepareSql = res.PrepareSql
+        result.Bindings = res.Bindings
+        result.RowsAffected += res.RowsAffected
+        if res.Err != nil {
+            result.Err = res.Err
+            break
+        }
+    }
+
+    q.result = result
+    return q.result
+}
+
+//field indexes of update columns, ptr of T.field or column name
+func (q *Query[T]) getBulkUpdateFields(columns []any) ([]int, error) {
+    tableStruct := q.tables[0].tableStruct
+    fieldNames, err := getStructFieldNameSlice(tableStruct.Interface())
+    if err != nil {
+        return nil, err
+    }
+
+    var ret []int
+    if len(columns) == 0 {
+        for i := 1; i < tableStruct.NumField(); i++ {
+            if fieldNames[i] != "" {
+                ret = append(ret, i)
+            }
+        }
+        return ret, nil
+    }
+
+    for _, v := range columns {
+        index := -1
+        if name, ok := v.(string); ok {
+            index = sliceContainIndex(fieldNames, strings.Trim(name, "`"))
+        } else {
+            for i := 0; i < tableStruct.NumField(); i++ {
+                if tableStruct.Field(i).Addr().Interface() == v {
+                    index = i
+                    break
+                }
+            }
+        }
+        if index < 0 || fieldNames[index] == "" {
+            return nil, ErrColumnNotExisted
+        }
+        ret = append(ret, index)
+    }
+    return ret, nil
+}
@@ -0,0 +1,120 @@
+package orm
+
+import (
+    "database/sql/driver"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestBulkUpdate(t *testing.T) {
+    rows := []*testRow{{Id: 1, Name: "a", Data: "x"}, {Id: 2, Name: "b", Data: "y"}, {Id: 3, Name: "c"}}
+    table := new(testRow)
+
+    tests := []struct {
+        name string
+        run  func(query *Query[*testRow]) QueryResult
+        want []fakeStatement
+    }{
+        {
+            name: "one column",
+            run: func(query *Query[*testRow]) QueryResult {
+                return query.BulkUpdate(rows[:2], &query.T.Name)
+            },
+            want: []fakeStatement{{
+                sql:  "update mydb.test_row set mydb.test_row.`name` = (case mydb.test_row.`id` when ? then ? when ? then ? else mydb.test_row.`name` end) where mydb.test_row.`id` in (?,?)",
+                args: []any{1, "a", 2, "b", 1, 2},
+            }},
+        },
+        {
+            name: "all columns except primary",
+            run: func(query *Query[*testRow]) QueryResult {
+                return query.Where("name", "a").BulkUpdate(rows[:1])
+            },
+            want: []fakeStatement{{
+                sql: "update mydb.test_row set mydb.test_row.`name` = (case mydb.test_row.`id` when ? then ? else mydb.test_row.`name` end)," +
+                    "mydb.test_row.`data` = (case mydb.test_row.`id` when ? then ? else mydb.test_row.`data` end) where name = ? and mydb.test_row.`id` in (?)",
+                args: []any{1, "a", 1, "x", "a", 1},
+            }},
+        },
+        {
+            name: "batches",
+            run: func(query *Query[*testRow]) QueryResult {
+                return query.BatchSize(2).BulkUpdate(rows, &query.T.Name, "data")
+            },
+            want: []fakeStatement{{
+                sql: "update mydb.test_row set mydb.test_row.`name` = (case mydb.test_row.`id` when ? then ? when ? then ? else mydb.test_row.`name` end)," +
+                    "mydb.test_row.`data` = (case mydb.test_row.`id` when ? then ? when ? then ? else mydb.test_row.`data` end) where mydb.test_row.`id` in (?,?)",
+                args: []any{1, "a", 2, "b", 1, "x", 2, "y", 1, 2},
+            }, {
+                sql: "update mydb.test_row set mydb.test_row.`name` = (case mydb.test_row.`id` when ? then ? else mydb.test_row.`name` end)," +
+                    "mydb.test_row.`data` = (case mydb.test_row.`id` when ? then ? else mydb.test_row.`data` end) where mydb.test_row.`id` in (?)",
+                args: []any{3, "c", 3, "", 3},
+            }},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            db, fake := newFakeDB()
+            res := tt.run(NewQuery(table, db))
+            if res.Err != nil {
+                t.Fatal(res.Err)
+            }
+
+            got := fake.recorded()
+            if len(got) != len(tt.want) {
+                t.Fatalf("want %d statements, got %v", len(tt.want), got)
+            }
+            for k, v := range tt.want {
+                if got[k].sql != v.sql {
+                    t.Errorf("statement %d\nwant: %s\ngot:  %s", k, v.sql, got[k].sql)
+                }
+                if reflect.DeepEqual(got[k].args, v.args) == false {
+                    t.Errorf("statement %d args\nwant: %#v\ngot:  %#v", k, v.args, got[k].args)
+                }
+            }
+        })
+    }
+}
+
+func TestBulkUpdateRowsAffected(t *testing.T) {
+    db, fake := newFakeDB()
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        if len(fake.recorded()) > 2 {
+            return nil, errors.New("lock wait timeout")
+        }
+        return driver.RowsAffected(1), nil
+    }
+
+    rows := []*testRow{{Id: 1}, {Id: 2}, {Id: 3}}
+    res := NewQuery(new(testRow), db).BatchSize(1).BulkUpdate(rows, "name")
+    if res.Err == nil || res.RowsAffected != 2 {
+        t.Errorf("want 2 rows affected before error, got %d, err %v", res.RowsAffected, res.Err)
+    }
+    if len(fake.recorded()) != 3 {
+        t.Errorf("want to stop after failed batch, got %d statements", len(fake.recorded()))
+    }
+}
+
+func TestBulkUpdateColumnNotExisted(t *testing.T) {
+    db, fake := newFakeDB()
+    res := NewQuery(new(testRow), db).BulkUpdate([]*testRow{{Id: 1}}, "nickname")
+    if errors.Is(res.Err, ErrColumnNotExisted) == false {
+        t.Errorf("want ErrColumnNotExisted, got %v", res.Err)
+    }
+    if len(fake.recorded()) > 0 {
+        t.Errorf("want no statement, got %v", fake.recorded())
+    }
+}
+
+func TestBulkUpdateSubQuery(t *testing.T) {
+    db, fake := newFakeDB()
+    res := newQueryRaw("sub", db).BulkUpdate([]*SubQuery{{}})
+    if res.Err == nil || res.Err.Error() != "slice elem must be T" {
+        t.Errorf("want error of slice elem, got %v", res.Err)
+    }
+    if len(fake.recorded()) > 0 {
+        t.Errorf("want no statement, got %v", fake.recorded())
+    }
+}
@@ -36,6 +36,15 @@ func reflectValueIsOrmField(v reflect.Value) bool {
     return false
 }
 
+func isIntegerKind(k reflect.Kind) bool {
+    switch k {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return true
+    }
+    return false
+}
+
 var structFieldsCache sync.Map
 
 func getFieldsCache(key string) []string {
@@ -0,0 +1,405 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "fmt"
+    "hash/crc32"
+    "reflect"
+    "sort"
+    "strconv"
+    "time"
+)
+
+//physical table of a sharded table
+type Shard struct {
+    TableName string    //table name of shard, TableName() of table if empty
+    DBs       []*sql.DB //write and read dbs of shard, Connections() of table if empty
+}
+
+func (s Shard) id() string {
+    if len(s.DBs) > 0 {
+        return s.TableName + "@" + fmt.Sprintf("%p", s.DBs[0])
+    }
+    return s.TableName
+}
+
+//locate shard by shard key value
+type ShardStrategy interface {
+    Shard(key any) (Shard, error)
+    Shards() []Shard
+}
+
+//table split into shards, query routed by shard key found in Where, WherePrimary, Insert
+type ShardTable interface {
+    ShardKey() any //ptr of T.field, like &t.UserId
+    ShardStrategy() ShardStrategy
+}
+
+//shard = key % count, table name like fmt.Sprintf("orders_%02d", shard)
+//dbs[shard % len(dbs)] as dbs of shard if not empty
+type ModuloShardStrategy struct {
+    shards []Shard
+}
+
+func NewModuloShardStrategy(count int, tableFormat string, dbs ...[]*sql.DB) *ModuloShardStrategy {
+    s := &ModuloShardStrategy{shards: make([]Shard, count)}
+    for i := range s.shards {
+        s.shards[i].TableName = fmt.Sprintf(tableFormat, i)
+        if len(dbs) > 0 {
+            s.shards[i].DBs = dbs[i%len(dbs)]
+        }
+    }
+    return s
+}
+
+func (s *ModuloShardStrategy) Shard(key any) (Shard, error) {
+    if len(s.shards) == 0 {
+        return Shard{}, ErrShardNotFound
+    }
+    n, err := shardKeyHash(key)
+    if err != nil {
+        return Shard{}, err
+    }
+    return s.shards[n%uint64(len(s.shards))], nil
+}
+
+func (s *ModuloShardStrategy) Shards() []Shard {
+    return s.shards
+}
+
+//shard of key less than Max
+type ShardRange struct {
+    Max   int64
+    Shard Shard
+}
+
+//shard by integer or time (unix seconds) key in ranges
+type RangeShardStrategy struct {
+    ranges []ShardRange
+}
+
+func NewRangeShardStrategy(ranges ...ShardRange) *RangeShardStrategy {
+    s := &RangeShardStrategy{ranges: append([]ShardRange{}, ranges...)}
+    sort.SliceStable(s.ranges, func(i, j int) bool {
+        return s.ranges[i].Max < s.ranges[j].Max
+    })
+    return s
+}
+
+func (s *RangeShardStrategy) Shard(key any) (Shard, error) {
+    n, err := shardKeyInt(key)
+    if err != nil {
+        return Shard{}, err
+    }
+    for _, v := range s.ranges {
+        if n < v.Max {
+            return v.Shard, nil
+        }
+    }
+    return Shard{}, ErrShardNotFound
+}
+
+func (s *RangeShardStrategy) Shards() []Shard {
+    ret := make([]Shard, len(s.ranges))
+    for k, v := range s.ranges {
+        ret[k] = v.Shard
+    }
+    return ret
+}
+
+//shard by crc32 hash ring, less keys moved while shards added or removed
+type ConsistentHashShardStrategy struct {
+    shards []Shard
+    ring   []uint32
+    nodes  map[uint32]int
+}
+
+//virtualNodes: nodes of each shard on hash ring, 100 if not positive
+func NewConsistentHashShardStrategy(virtualNodes int, shards ...Shard) *ConsistentHashShardStrategy {
+    if virtualNodes <= 0 {
+        virtualNodes = 100
+    }
+    s := &ConsistentHashShardStrategy{shards: shards, nodes: make(map[uint32]int)}
+    for k, v := range shards {
+        for i := 0; i < virtualNodes; i++ {
+            hash := crc32.ChecksumIEEE([]byte(v.TableName + "#" + strconv.Itoa(k) + "#" + strconv.Itoa(i)))
+            if _, ok := s.nodes[hash]; ok {
+                continue
+            }
+            s.nodes[hash] = k
+            s.ring = append(s.ring, hash)
+        }
+    }
+    sort.Slice(s.ring, func(i, j int) bool {
+        return s.ring[i] < s.ring[j]
+    })
+    return s
+}
+
+func (s *ConsistentHashShardStrategy) Shard(key any) (Shard, error) {
+    if len(s.ring) == 0 {
+        return Shard{}, ErrShardNotFound
+    }
+    str, err := shardKeyString(key)
+    if err != nil {
+        return Shard{}, err
+    }
+    hash := crc32.ChecksumIEEE([]byte(str))
+    index := sort.Search(len(s.ring), func(i int) bool {
+        return s.ring[i] >= hash
+    })
+    if index == len(s.ring) {
+        index = 0
+    }
+    return s.shards[s.nodes[s.ring[index]]], nil
+}
+
+func (s *ConsistentHashShardStrategy) Shards() []Shard {
+    return s.shards
+}
+
+func shardKeyValue(key any) (any, error) {
+    if valuer, ok := key.(driver.Valuer); ok {
+        return valuer.Value()
+    }
+    rv := reflect.ValueOf(key)
+    if rv.Kind() == reflect.Ptr {
+        if rv.IsNil() {
+            return nil, ErrShardKeyInvalid
+        }
+        return shardKeyValue(rv.Elem().Interface())
+    }
+    return key, nil
+}
+
+func shardKeyInt(key any) (int64, error) {
+    val, err := shardKeyValue(key)
+    if err != nil {
+        return 0, err
+    }
+    if t, ok := val.(time.Time); ok {
+        return t.Unix(), nil
+    }
+    rv := reflect.ValueOf(val)
+    switch rv.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return rv.Int(), nil
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return int64(rv.Uint()), nil
+    case reflect.String:
+        n, err := strconv.ParseInt(rv.String(), 10, 64)
+        if err != nil {
+            return 0, ErrShardKeyInvalid
+        }
+        return n, nil
+    }
+    return 0, ErrShardKeyInvalid
+}
+
+//integer key as it is, others by crc32
+func shardKeyHash(key any) (uint64, error) {
+    n, err := shardKeyInt(key)
+    if err == nil {
+        if n < 0 {
+            n = -n
+        }
+        return uint64(n), nil
+    }
+    str, err := shardKeyString(key)
+    if err != nil {
+        return 0, err
+    }
+    return uint64(crc32.ChecksumIEEE([]byte(str))), nil
+}
+
+func shardKeyString(key any) (string, error) {
+    val, err := shardKeyValue(key)
+    if err != nil {
+        return "", err
+    }
+    switch v := val.(type) {
+    case nil:
+        return "", ErrShardKeyInvalid
+    case string:
+        return v, nil
+    case []byte:
+        return string(v), nil
+    }
+    return fmt.Sprint(val), nil
+}
+
+//route query to shard of key
+func (q *Query[T]) Shard(key any) *Query[T] {
+    shardTable, ok := q.tables[0].table.(ShardTable)
+    if ok == false {
+        return q.setErr(ErrTableNotSharded)
+    }
+    shard, err := shardTable.ShardStrategy().Shard(key)
+    if err != nil {
+        return q.setErr(err)
+    }
+    q.useShard(shard)
+    return q
+}
+
+func (q *Query[T]) useShard(shard Shard) {
+    //copy tables, not shared with clones
+    q.tables = append([]*queryTable{}, q.tables...)
+    table := *q.tables[0]
+    table.shard = &shard
+    q.tables[0] = &table
+
+    if len(shard.DBs) > 0 {
+        q.writeAndReadDbs = shard.DBs
+    }
+}
+
+//route query to shard by shard key in where conditions, if not routed yet
+func (q *Query[T]) resolveShard() {
+    if len(q.tables) == 0 || q.tables[0].shard != nil {
+        return
+    }
+    shardTable, ok := q.tables[0].table.(ShardTable)
+    if ok == false {
+        return
+    }
+    keyColumn, err := q.parseColumn(shardTable.ShardKey())
+    if err != nil {
+        return
+    }
+
+    var shard *Shard
+    for k, v := range q.wheres {
+        if k > 0 && v.IsOr {
+            return
+        }
+        if v.Column != keyColumn || len(v.SubWheres) > 0 {
+            continue
+        }
+
+        var keys []any
+        if v.Raw == "" && v.Operator == string(WhereEqual) {
+            keys = []any{v.Val}
+        } else if v.Operator == string(WhereIn) && len(v.RawBindings) > 0 {
+            keys = v.RawBindings
+        }
+
+        for _, key := range keys {
+            temp, err := shardTable.ShardStrategy().Shard(key)
+            if err != nil {
+                q.setErr(err)
+                return
+            }
+            if shard != nil && shard.id() != temp.id() {
+                //keys in different shards
+                return
+            }
+            shard = &temp
+        }
+    }
+    if shard != nil {
+        q.useShard(*shard)
+    }
+}
+
+//shard table must be routed to a shard for update, delete and insert
+func (q *Query[T]) requireShard() {
+    q.resolveShard()
+    if _, ok := q.tables[0].table.(ShardTable); ok && q.tables[0].shard == nil {
+        q.setErr(ErrShardKeyRequired)
+    }
+}
+
+//route insert of rows in one shard, rows of different shards need insertInShards
+//columns prefixed by shard table name, insert statement has no table alias
+func (q *Query[T]) routeInsertShard(data any) {
+    if _, ok := q.tables[0].table.(ShardTable); ok == false {
+        return
+    }
+    if q.tables[0].shard == nil {
+        rows, ok := data.([]T)
+        if ok == false {
+            q.setErr(ErrShardKeyRequired)
+            return
+        }
+        shards, _, err := q.groupRowsByShard(rows)
+        if err != nil {
+            q.setErr(err)
+            return
+        } else if len(shards) != 1 {
+            q.setErr(ErrShardKeyRequired)
+            return
+        }
+        q.useShard(shards[0])
+    }
+    table := *q.tables[0]
+    table.alias = ""
+    q.tables[0] = &table
+}
+
+//shards in order of rows, rows grouped by shard id
+func (q *Query[T]) groupRowsByShard(data []T) ([]Shard, map[string][]T, error) {
+    shardTable, ok := q.tables[0].table.(ShardTable)
+    if ok == false {
+        return nil, nil, ErrTableNotSharded
+    }
+
+    keyIndex := -1
+    for i := 0; i < q.tables[0].tableStruct.NumField(); i++ {
+        if q.tables[0].tableStruct.Field(i).Addr().Interface() == shardTable.ShardKey() {
+            keyIndex = i
+            break
+        }
+    }
+    if keyIndex < 0 {
+        return nil, nil, ErrColumnNotExisted
+    }
+
+    var shards []Shard
+    groups := make(map[string][]T)
+    for _, v := range data {
+        rowVal := reflect.ValueOf(v)
+        if rowVal.IsNil() {
+            return nil, nil, ErrShardKeyInvalid
+        }
+        shard, err := shardTable.ShardStrategy().Shard(rowVal.Elem().Field(keyIndex).Interface())
+        if err != nil {
+            return nil, nil, err
+        }
+        if _, ok := groups[shard.id()]; ok == false {
+            shards = append(shards, shard)
+        }
+        groups[shard.id()] = append(groups[shard.id()], v)
+    }
+    return shards, groups, nil
+}
+
+//insert rows into their shards, by shard key of each row
+func (q *Query[T]) insertInShards(data []T, size int) QueryResult {
+    _, ok := q.tables[0].table.(ShardTable)
+    if ok == false || q.tables[0].shard != nil || len(data) == 0 {
+        return q.insertInBatches(data, size)
+    }
+
+    shards, groups, err := q.groupRowsByShard(data)
+    if err != nil {
+        q.setErr(err)
+        return q.result
+    }
+
+    var result QueryResult
+    for _, shard := range shards {
+        nq := q.Clone()
+        nq.useShard(shard)
+        res := nq.insertInBatches(groups[shard.id()], size)
+
+        mergeInsertResult(&result, res)
+        if res.Err != nil {
+            break
+        }
+    }
+
+    q.result = result
+    return q.result
+}
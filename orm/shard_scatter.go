@@ -0,0 +1,414 @@
+package orm
+
+import (
+    "bytes"
+    "database/sql/driver"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+//shards queried at the same time
+const scatterConcurrency = 16
+
+//query on ShardTable without shard key, run on every shard
+func (q *Query[T]) needScatter() bool {
+    if len(q.tables) == 0 || q.prepareSql != "" || q.tx != nil || q.self != nil {
+        return false
+    }
+    q.resolveShard()
+    if q.tables[0].shard != nil || q.tables[0].rawSql != "" {
+        return false
+    }
+    shardTable, ok := q.tables[0].table.(ShardTable)
+    return ok && len(shardTable.ShardStrategy().Shards()) > 0
+}
+
+//run query on every shard concurrently, merge results by order by, limit, offset
+//count|sum|max|min merged for single value and value of map, others need shard key
+func (q *Query[T]) scatterGetTo(destPtr any) QueryResult {
+    destValue := reflect.ValueOf(destPtr)
+    if destValue.Kind() != reflect.Ptr || destValue.Elem().Kind() == reflect.Ptr {
+        q.setErr(ErrDestOfGetToMustBePtr)
+        return q.result
+    }
+    aggregates, err := q.scatterAggregates()
+    if err == nil {
+        err = checkScatterMerge(destValue.Elem().Type(), aggregates, len(q.groupBy) > 0)
+    }
+    q.setErr(err)
+    if q.result.Err != nil {
+        return q.result
+    }
+
+    shards := q.tables[0].table.(ShardTable).ShardStrategy().Shards()
+    dests := make([]reflect.Value, len(shards))
+    results := make([]QueryResult, len(shards))
+
+    var wg sync.WaitGroup
+    sem := make(chan struct{}, scatterConcurrency)
+    for k, shard := range shards {
+        nq := q.Clone()
+        nq.useShard(shard)
+        if nq.limit > 0 {
+            nq.limit += nq.offset
+        }
+        nq.offset = 0
+        dests[k] = reflect.New(destValue.Elem().Type())
+
+        wg.Add(1)
+        sem <- struct{}{}
+        go func(k int, nq *Query[T]) {
+            defer func() {
+                <-sem
+                wg.Done()
+            }()
+            results[k] = nq.GetTo(dests[k].Interface())
+        }(k, nq)
+    }
+    wg.Wait()
+
+    q.result.PrepareSql = results[0].PrepareSql
+    q.result.Bindings = results[0].Bindings
+    for _, v := range results {
+        if v.Err != nil {
+            q.result.Err = v.Err
+            return q.result
+        }
+    }
+
+    switch destValue.Elem().Kind() {
+    case reflect.Slice:
+        merged := reflect.MakeSlice(destValue.Elem().Type(), 0, 0)
+        for _, v := range dests {
+            merged = reflect.AppendSlice(merged, v.Elem())
+        }
+        values := make([]reflect.Value, merged.Len())
+        for i := range values {
+            values[i] = merged.Index(i)
+        }
+        values = q.sortAndLimitScattered(values)
+
+        ret := reflect.MakeSlice(destValue.Elem().Type(), 0, len(values))
+        for _, v := range values {
+            ret = reflect.Append(ret, v)
+        }
+        destValue.Elem().Set(ret)
+        q.result.RowsAffected = int64(len(values))
+    case reflect.Map:
+        aggregate := ""
+        if len(aggregates) > 1 {
+            aggregate = aggregates[1]
+        }
+        merged := reflect.MakeMap(destValue.Elem().Type())
+        for _, v := range dests {
+            iter := v.Elem().MapRange()
+            for iter.Next() {
+                old := merged.MapIndex(iter.Key())
+                if old.IsValid() && aggregate != "" {
+                    //same group in different shards
+                    ret := reflect.New(old.Type()).Elem()
+                    ret.Set(old)
+                    if err := mergeScatterAggregate(aggregate, ret, iter.Value()); err != nil {
+                        q.result.Err = err
+                        return q.result
+                    }
+                    merged.SetMapIndex(iter.Key(), ret)
+                } else if old.IsValid() && old.Kind() == reflect.Slice {
+                    merged.SetMapIndex(iter.Key(), reflect.AppendSlice(old, iter.Value()))
+                } else {
+                    merged.SetMapIndex(iter.Key(), iter.Value())
+                }
+            }
+        }
+        destValue.Elem().Set(merged)
+        q.result.RowsAffected = int64(merged.Len())
+    default:
+        var values []reflect.Value
+        for k, v := range dests {
+            if results[k].RowsAffected > 0 {
+                values = append(values, v.Elem())
+            }
+        }
+        if len(values) == 0 {
+            return q.result
+        }
+
+        if len(aggregates) > 0 && aggregates[0] != "" {
+            ret := reflect.New(values[0].Type()).Elem()
+            ret.Set(values[0])
+            for _, v := range values[1:] {
+                if err := mergeScatterAggregate(aggregates[0], ret, v); err != nil {
+                    q.result.Err = err
+                    return q.result
+                }
+            }
+            destValue.Elem().Set(ret)
+            q.result.RowsAffected = 1
+            return q.result
+        }
+
+        values = q.sortAndLimitScattered(values)
+        if len(values) > 0 {
+            destValue.Elem().Set(values[0])
+            q.result.RowsAffected = 1
+        }
+    }
+    return q.result
+}
+
+var aggregateFuncRegexp = regexp.MustCompile(`\b(count|sum|max|min|avg|group_concat|std|stddev|stddev_pop|stddev_samp|variance|var_pop|var_samp|bit_and|bit_or|bit_xor|json_arrayagg|json_objectagg)\s*\(\s*(distinct\s)?`)
+
+//merge of selected columns: sum for count|sum, max, min, empty if not aggregate
+//ErrShardKeyRequired for aggregates not mergeable, like avg, count(distinct), sum(a) / count(b)
+func (q *Query[T]) scatterAggregates() ([]string, error) {
+    ret := make([]string, len(q.columns))
+    for k, v := range q.columns {
+        column, ok := q.isStringOrRaw(v)
+        if ok == false {
+            continue
+        }
+        column = strings.ToLower(strings.TrimSpace(column))
+        matches := aggregateFuncRegexp.FindAllStringSubmatchIndex(column, -1)
+        if len(matches) == 0 {
+            continue
+        }
+        if len(matches) > 1 || matches[0][0] != 0 || matches[0][4] >= 0 {
+            return nil, ErrShardKeyRequired
+        }
+        switch name := column[matches[0][2]:matches[0][3]]; name {
+        case "count", "sum":
+            ret[k] = "sum"
+        case "max", "min":
+            ret[k] = name
+        default:
+            return nil, ErrShardKeyRequired
+        }
+    }
+    return ret, nil
+}
+
+//results of shards mergeable into dest
+//aggregated or grouped rows of slice, struct: same group in different shards not merged
+func checkScatterMerge(destType reflect.Type, aggregates []string, grouped bool) error {
+    aggregated := false
+    for _, v := range aggregates {
+        if v != "" {
+            aggregated = true
+        }
+    }
+    switch destType.Kind() {
+    case reflect.Slice:
+        if aggregated || grouped {
+            return ErrShardKeyRequired
+        }
+    case reflect.Map:
+        if aggregated == false {
+            return nil
+        }
+        switch destType.Elem().Kind() {
+        case reflect.Struct, reflect.Ptr, reflect.Slice, reflect.Map:
+            return ErrShardKeyRequired
+        }
+        if len(aggregates) != 2 || aggregates[0] != "" {
+            return ErrShardKeyRequired
+        }
+    default:
+        if aggregated && (len(aggregates) > 1 || aggregates[0] == "") {
+            return ErrShardKeyRequired
+        }
+    }
+    return nil
+}
+
+//merge aggregate of another shard into dest
+func mergeScatterAggregate(aggregate string, dest, v reflect.Value) error {
+    switch aggregate {
+    case "sum":
+        if isNumberKind(dest.Kind()) == false {
+            return ErrShardKeyRequired
+        }
+        addNumberValue(dest, v)
+    case "max":
+        if compareValues(v.Interface(), dest.Interface()) > 0 {
+            dest.Set(v)
+        }
+    case "min":
+        if compareValues(v.Interface(), dest.Interface()) < 0 {
+            dest.Set(v)
+        }
+    }
+    return nil
+}
+
+func (q *Query[T]) sortAndLimitScattered(values []reflect.Value) []reflect.Value {
+    if len(q.orderbys) > 0 {
+        type orderBy struct {
+            name string
+            desc bool
+        }
+        orders := make([]orderBy, len(q.orderbys))
+        for k, v := range q.orderbys {
+            lower := strings.ToLower(v)
+            if strings.HasSuffix(lower, " desc") {
+                orders[k] = orderBy{name: columnName(strings.TrimSpace(v[:len(v)-5])), desc: true}
+            } else if strings.HasSuffix(lower, " asc") {
+                orders[k] = orderBy{name: columnName(strings.TrimSpace(v[:len(v)-4]))}
+            } else {
+                orders[k] = orderBy{name: columnName(strings.TrimSpace(v))}
+            }
+        }
+
+        sort.SliceStable(values, func(i, j int) bool {
+            for _, v := range orders {
+                c := compareValues(scatterSortValue(values[i], v.name), scatterSortValue(values[j], v.name))
+                if c == 0 {
+                    continue
+                }
+                if v.desc {
+                    return c > 0
+                }
+                return c < 0
+            }
+            return false
+        })
+    }
+
+    if q.offset > 0 {
+        if q.offset >= len(values) {
+            return nil
+        }
+        values = values[q.offset:]
+    }
+    if q.limit > 0 && q.limit < len(values) {
+        values = values[:q.limit]
+    }
+    return values
+}
+
+//field of struct by column name, or value itself
+func scatterSortValue(v reflect.Value, column string) any {
+    elem := reflect.Indirect(v)
+    if elem.Kind() == reflect.Struct && reflectValueIsOrmField(v) == false {
+        names, err := getStructFieldNameSlice(elem.Interface())
+        if err == nil {
+            index := sliceContainIndex(names, column)
+            if index >= 0 {
+                return elem.Field(index).Interface()
+            }
+        }
+        return nil
+    }
+    return v.Interface()
+}
+
+func isNumberKind(k reflect.Kind) bool {
+    return isIntegerKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func addNumberValue(dest, v reflect.Value) {
+    switch dest.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        dest.SetInt(dest.Int() + v.Int())
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        dest.SetUint(dest.Uint() + v.Uint())
+    case reflect.Float32, reflect.Float64:
+        dest.SetFloat(dest.Float() + v.Float())
+    }
+}
+
+//-1, 0, 1, nil is the smallest
+func compareValues(a, b any) int {
+    a, b = comparableValue(a), comparableValue(b)
+    if a == nil || b == nil {
+        if a == nil && b == nil {
+            return 0
+        } else if a == nil {
+            return -1
+        }
+        return 1
+    }
+
+    switch av := a.(type) {
+    case time.Time:
+        if bv, ok := b.(time.Time); ok {
+            if av.Before(bv) {
+                return -1
+            } else if av.After(bv) {
+                return 1
+            }
+            return 0
+        }
+    case []byte:
+        if bv, ok := b.([]byte); ok {
+            return bytes.Compare(av, bv)
+        }
+    }
+
+    ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+    switch {
+    case ra.CanInt() && rb.CanInt():
+        if ra.Int() < rb.Int() {
+            return -1
+        } else if ra.Int() > rb.Int() {
+            return 1
+        }
+        return 0
+    case ra.CanUint() && rb.CanUint():
+        if ra.Uint() < rb.Uint() {
+            return -1
+        } else if ra.Uint() > rb.Uint() {
+            return 1
+        }
+        return 0
+    case isNumberKind(ra.Kind()) && isNumberKind(rb.Kind()):
+        fa, fb := numberValue(ra), numberValue(rb)
+        if fa < fb {
+            return -1
+        } else if fa > fb {
+            return 1
+        }
+        return 0
+    case ra.Kind() == reflect.String && rb.Kind() == reflect.String:
+        return strings.Compare(ra.String(), rb.String())
+    }
+    return strings.Compare(varToString(a), varToString(b))
+}
+
+func comparableValue(v any) any {
+    if valuer, ok := v.(driver.Valuer); ok {
+        rv := reflect.ValueOf(v)
+        if rv.Kind() == reflect.Ptr && rv.IsNil() {
+            return nil
+        }
+        if _, ok := v.(time.Time); ok == false {
+            temp, err := valuer.Value()
+            if err == nil {
+                return temp
+            }
+        }
+    }
+    rv := reflect.ValueOf(v)
+    if rv.Kind() == reflect.Ptr {
+        if rv.IsNil() {
+            return nil
+        }
+        return comparableValue(rv.Elem().Interface())
+    }
+    return v
+}
+
+func numberValue(v reflect.Value) float64 {
+    switch v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(v.Int())
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return float64(v.Uint())
+    case reflect.Float32, reflect.Float64:
+        return v.Float()
+    }
+    return 0
+}
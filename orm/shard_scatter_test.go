@@ -0,0 +1,239 @@
+package orm
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestCompareValues(t *testing.T) {
+    one, two := 1, 2
+    now := time.Now()
+
+    tests := []struct {
+        name string
+        a, b any
+        want int
+    }{
+        {name: "int less", a: 1, b: 2, want: -1},
+        {name: "int equal", a: int64(2), b: 2, want: 0},
+        {name: "uint greater", a: uint(3), b: uint8(2), want: 1},
+        {name: "float", a: 1.5, b: 2, want: -1},
+        {name: "int and float", a: 3, b: 2.5, want: 1},
+        {name: "string", a: "b", b: "a", want: 1},
+        {name: "bytes", a: []byte("a"), b: []byte("b"), want: -1},
+        {name: "time", a: now, b: now.Add(time.Second), want: -1},
+        {name: "time equal", a: now, b: now, want: 0},
+        {name: "ptr", a: &two, b: &one, want: 1},
+        {name: "nil smallest", a: nil, b: 0, want: -1},
+        {name: "nil ptr smallest", a: 0, b: (*int)(nil), want: 1},
+        {name: "nil equal", a: nil, b: (*int)(nil), want: 0},
+        {name: "valuer", a: sql.NullInt64{Int64: 5, Valid: true}, b: 4, want: 1},
+        {name: "null valuer", a: sql.NullString{}, b: "", want: -1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := compareValues(tt.a, tt.b); got != tt.want {
+                t.Errorf("want %d, got %d", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestScatterAggregates(t *testing.T) {
+    tests := []struct {
+        columns []any
+        want    []string
+        err     error
+    }{
+        {columns: nil, want: []string{}},
+        {columns: []any{"id", "status"}, want: []string{"", ""}},
+        {columns: []any{"count(*)"}, want: []string{"sum"}},
+        {columns: []any{"status", "COUNT(id) as c"}, want: []string{"", "sum"}},
+        {columns: []any{"sum(amount)", "max(created_at)", "min(id)"}, want: []string{"sum", "max", "min"}},
+        {columns: []any{"avg(amount)"}, err: ErrShardKeyRequired},
+        {columns: []any{"count(distinct user_id)"}, err: ErrShardKeyRequired},
+        {columns: []any{"sum(distinct amount)"}, err: ErrShardKeyRequired},
+        {columns: []any{"group_concat(status)"}, err: ErrShardKeyRequired},
+        {columns: []any{"sum(amount) / count(id)"}, err: ErrShardKeyRequired},
+        {columns: []any{"ifnull(sum(amount), 0)"}, err: ErrShardKeyRequired},
+    }
+
+    for _, tt := range tests {
+        t.Run(fmt.Sprint(tt.columns), func(t *testing.T) {
+            q := NewQuery(new(testOrder))
+            q.columns = tt.columns
+            got, err := q.scatterAggregates()
+            if errors.Is(err, tt.err) == false {
+                t.Fatalf("want err %v, got %v", tt.err, err)
+            }
+            if tt.err == nil && reflect.DeepEqual(got, tt.want) == false {
+                t.Errorf("want %v, got %v", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestCheckScatterMerge(t *testing.T) {
+    tests := []struct {
+        name       string
+        dest       any
+        aggregates []string
+        grouped    bool
+        err        error
+    }{
+        {name: "rows", dest: []testOrder{}, aggregates: []string{}},
+        {name: "grouped rows", dest: []string{}, aggregates: []string{""}, grouped: true, err: ErrShardKeyRequired},
+        {name: "aggregated rows", dest: []int{}, aggregates: []string{"sum"}, err: ErrShardKeyRequired},
+        {name: "map of rows", dest: map[int]testOrder{}, aggregates: []string{}},
+        {name: "map of counts", dest: map[string]int{}, aggregates: []string{"", "sum"}, grouped: true},
+        {name: "map of aggregated structs", dest: map[string]testOrder{}, aggregates: []string{"", "sum"}, err: ErrShardKeyRequired},
+        {name: "map of aggregated slices", dest: map[string][]int{}, aggregates: []string{"", "sum"}, err: ErrShardKeyRequired},
+        {name: "map keyed by aggregate", dest: map[int]string{}, aggregates: []string{"sum", ""}, err: ErrShardKeyRequired},
+        {name: "count", dest: 0, aggregates: []string{"sum"}},
+        {name: "struct of aggregates", dest: testOrder{}, aggregates: []string{"sum", "max"}, err: ErrShardKeyRequired},
+        {name: "first row", dest: testOrder{}, aggregates: []string{"", ""}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := checkScatterMerge(reflect.TypeOf(tt.dest), tt.aggregates, tt.grouped)
+            if errors.Is(err, tt.err) == false {
+                t.Errorf("want err %v, got %v", tt.err, err)
+            }
+        })
+    }
+}
+
+func TestMergeScatterAggregate(t *testing.T) {
+    early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    late := early.Add(time.Hour)
+
+    tests := []struct {
+        aggregate string
+        dest, v   any
+        want      any
+        err       error
+    }{
+        {aggregate: "sum", dest: int64(2), v: int64(3), want: int64(5)},
+        {aggregate: "sum", dest: uint(2), v: uint(3), want: uint(5)},
+        {aggregate: "sum", dest: 1.5, v: 2.0, want: 3.5},
+        {aggregate: "sum", dest: "a", v: "b", err: ErrShardKeyRequired},
+        {aggregate: "max", dest: 2, v: 3, want: 3},
+        {aggregate: "max", dest: late, v: early, want: late},
+        {aggregate: "min", dest: "b", v: "a", want: "a"},
+        {aggregate: "min", dest: early, v: late, want: early},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.aggregate+" "+fmt.Sprint(tt.dest, tt.v), func(t *testing.T) {
+            dest := reflect.New(reflect.TypeOf(tt.dest)).Elem()
+            dest.Set(reflect.ValueOf(tt.dest))
+            err := mergeScatterAggregate(tt.aggregate, dest, reflect.ValueOf(tt.v))
+            if errors.Is(err, tt.err) == false {
+                t.Fatalf("want err %v, got %v", tt.err, err)
+            }
+            if tt.err == nil && reflect.DeepEqual(dest.Interface(), tt.want) == false {
+                t.Errorf("want %v, got %v", tt.want, dest.Interface())
+            }
+        })
+    }
+}
+
+//fake db answering each shard table with its own rows
+func newScatterDB() (*sql.DB, *fakeDB) {
+    shardRows := map[string][][]driver.Value{
+        "orders_00": {{int64(4), int64(4), "paid"}, {int64(8), int64(8), "new"}},
+        "orders_01": {{int64(1), int64(1), "new"}, {int64(5), int64(5), "paid"}},
+        "orders_02": {{int64(2), int64(2), "paid"}},
+        "orders_03": nil,
+    }
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        for table, rows := range shardRows {
+            if strings.Contains(sqlStr, "mydb."+table+" ") == false {
+                continue
+            }
+            if strings.Contains(sqlStr, "count(*)") {
+                return fakeValueRows("count(*)", int64(len(rows))), nil
+            }
+            return &fakeRows{columns: []string{"id", "user_id", "status"}, rows: rows}, nil
+        }
+        return &fakeRows{}, nil
+    }
+    return db, fake
+}
+
+func TestScatterGets(t *testing.T) {
+    table := new(testOrder)
+
+    t.Run("every shard queried", func(t *testing.T) {
+        db, fake := newScatterDB()
+        rows, res := NewQuery(table, db).Gets()
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        if len(rows) != 5 || res.RowsAffected != 5 {
+            t.Errorf("want 5 rows, got %d, rows affected %d", len(rows), res.RowsAffected)
+        }
+        if len(fake.recorded()) != 4 {
+            t.Errorf("want 4 statements, got %d", len(fake.recorded()))
+        }
+    })
+
+    t.Run("merged by order by and limit", func(t *testing.T) {
+        db, fake := newScatterDB()
+        rows, res := NewQuery(table, db).OrderByDesc(&table.Id).Limit(2).Offset(1).Gets()
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        var ids []int
+        for _, v := range rows {
+            ids = append(ids, v.Id)
+        }
+        if reflect.DeepEqual(ids, []int{5, 4}) == false {
+            t.Errorf("want ids [5 4], got %v", ids)
+        }
+        for _, v := range fake.recorded() {
+            if strings.HasSuffix(strings.TrimSpace(v.sql), "limit 3") == false {
+                t.Errorf("want limit plus offset on each shard, got %s", v.sql)
+            }
+        }
+    })
+
+    t.Run("count summed", func(t *testing.T) {
+        db, _ := newScatterDB()
+        count, res := NewQuery(table, db).GetCount()
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        if count != 5 {
+            t.Errorf("want 5, got %d", count)
+        }
+    })
+
+    t.Run("routed by shard key", func(t *testing.T) {
+        db, fake := newScatterDB()
+        rows, res := NewQuery(table, db).Where(&table.UserId, 5).Gets()
+        if res.Err != nil {
+            t.Fatal(res.Err)
+        }
+        if len(rows) != 2 || len(fake.recorded()) != 1 {
+            t.Errorf("want 2 rows by 1 statement, got %d rows by %d", len(rows), len(fake.recorded()))
+        }
+    })
+
+    t.Run("group by count needs shard key", func(t *testing.T) {
+        db, _ := newScatterDB()
+        _, res := NewQuery(table, db).GroupBy(&table.Status).GetCount()
+        if errors.Is(res.Err, ErrShardKeyRequired) == false {
+            t.Errorf("want ErrShardKeyRequired, got %v", res.Err)
+        }
+    })
+}
@@ -0,0 +1,345 @@
+package orm
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "hash/crc32"
+    "strings"
+    "testing"
+    "time"
+)
+
+var testOrderShards = NewModuloShardStrategy(4, "orders_%02d")
+
+type testOrder struct {
+    Id     int    `json:"id"`
+    UserId int    `json:"user_id"`
+    Status string `json:"status"`
+}
+
+func (*testOrder) Connections() []*sql.DB {
+    return nil
+}
+
+func (*testOrder) DatabaseName() string {
+    return "mydb"
+}
+
+func (*testOrder) TableName() string {
+    return "orders"
+}
+
+func (o *testOrder) ShardKey() any {
+    return &o.UserId
+}
+
+func (*testOrder) ShardStrategy() ShardStrategy {
+    return testOrderShards
+}
+
+func TestModuloShardStrategy(t *testing.T) {
+    userId := 6
+    var nilUserId *int
+
+    tests := []struct {
+        name string
+        key  any
+        want string
+        err  error
+    }{
+        {name: "int", key: 1, want: "orders_01"},
+        {name: "wrapped", key: 65, want: "orders_01"},
+        {name: "negative", key: -5, want: "orders_01"},
+        {name: "uint", key: uint8(3), want: "orders_03"},
+        {name: "ptr", key: &userId, want: "orders_02"},
+        {name: "integer string", key: "10", want: "orders_02"},
+        {name: "string", key: "abc", want: fmt.Sprintf("orders_%02d", crc32.ChecksumIEEE([]byte("abc"))%4)},
+        {name: "valuer", key: sql.NullInt64{Int64: 7, Valid: true}, want: "orders_03"},
+        {name: "null valuer", key: sql.NullInt64{}, err: ErrShardKeyInvalid},
+        {name: "nil ptr", key: nilUserId, err: ErrShardKeyInvalid},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            shard, err := testOrderShards.Shard(tt.key)
+            if errors.Is(err, tt.err) == false {
+                t.Fatalf("want err %v, got %v", tt.err, err)
+            }
+            if shard.TableName != tt.want {
+                t.Errorf("want %s, got %s", tt.want, shard.TableName)
+            }
+        })
+    }
+
+    if _, err := NewModuloShardStrategy(0, "orders_%02d").Shard(1); errors.Is(err, ErrShardNotFound) == false {
+        t.Errorf("no shards: want ErrShardNotFound, got %v", err)
+    }
+}
+
+func TestRangeShardStrategy(t *testing.T) {
+    s := NewRangeShardStrategy(
+        ShardRange{Max: 1704067200, Shard: Shard{TableName: "logs_2023"}}, //2024-01-01
+        ShardRange{Max: 100, Shard: Shard{TableName: "logs_old"}},
+    )
+
+    tests := []struct {
+        name string
+        key  any
+        want string
+        err  error
+    }{
+        {name: "first range", key: 99, want: "logs_old"},
+        {name: "max excluded", key: 100, want: "logs_2023"},
+        {name: "time", key: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), want: "logs_2023"},
+        {name: "out of ranges", key: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), err: ErrShardNotFound},
+        {name: "not integer", key: "abc", err: ErrShardKeyInvalid},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            shard, err := s.Shard(tt.key)
+            if errors.Is(err, tt.err) == false {
+                t.Fatalf("want err %v, got %v", tt.err, err)
+            }
+            if shard.TableName != tt.want {
+                t.Errorf("want %s, got %s", tt.want, shard.TableName)
+            }
+        })
+    }
+
+    shards := s.Shards()
+    if len(shards) != 2 || shards[0].TableName != "logs_old" || shards[1].TableName != "logs_2023" {
+        t.Errorf("shards not sorted by max: %v", shards)
+    }
+}
+
+func TestConsistentHashShardStrategy(t *testing.T) {
+    shards := []Shard{{TableName: "t_a"}, {TableName: "t_b"}, {TableName: "t_c"}}
+    s := NewConsistentHashShardStrategy(0, shards...)
+    //last shard removed
+    fewer := NewConsistentHashShardStrategy(0, shards[:2]...)
+
+    used := make(map[string]int)
+    moved := 0
+    for i := 0; i < 3000; i++ {
+        key := "user" + fmt.Sprint(i)
+        shard, err := s.Shard(key)
+        if err != nil {
+            t.Fatal(err)
+        }
+        again, _ := s.Shard(key)
+        if again.TableName != shard.TableName {
+            t.Fatalf("%s routed to %s then %s", key, shard.TableName, again.TableName)
+        }
+        used[shard.TableName]++
+
+        after, _ := fewer.Shard(key)
+        if after.TableName != shard.TableName {
+            moved++
+            if shard.TableName != "t_c" {
+                t.Errorf("%s moved from %s to %s", key, shard.TableName, after.TableName)
+            }
+        }
+    }
+    for _, v := range shards {
+        if used[v.TableName] < 500 {
+            t.Errorf("shard %s got %d of 3000 keys", v.TableName, used[v.TableName])
+        }
+    }
+    if moved != used["t_c"] {
+        t.Errorf("want %d keys moved, got %d", used["t_c"], moved)
+    }
+
+    if _, err := NewConsistentHashShardStrategy(0).Shard(1); errors.Is(err, ErrShardNotFound) == false {
+        t.Errorf("no shards: want ErrShardNotFound, got %v", err)
+    }
+}
+
+func TestShardRouting(t *testing.T) {
+    table := new(testOrder)
+
+    tests := []struct {
+        name string
+        run  func(query *Query[*testOrder]) QueryResult
+        want []string
+    }{
+        {
+            name: "select routed by where",
+            run: func(query *Query[*testOrder]) QueryResult {
+                _, res := query.Where(&table.UserId, 5).Gets()
+                return res
+            },
+            want: []string{"select * from mydb.orders_01 orders where orders.`user_id` = ?"},
+        },
+        {
+            name: "select routed by where in",
+            run: func(query *Query[*testOrder]) QueryResult {
+                _, res := query.Where(&table.UserId, WhereIn, []int{2, 6}).Gets()
+                return res
+            },
+            want: []string{"select * from mydb.orders_02 orders where orders.`user_id` in (?,?)"},
+        },
+        {
+            name: "update routed by where",
+            run: func(query *Query[*testOrder]) QueryResult {
+                return query.Where(&table.UserId, 5).Update(&table.Status, "paid")
+            },
+            want: []string{"update mydb.orders_01 orders set orders.`status` = ? where orders.`user_id` = ?"},
+        },
+        {
+            name: "delete by shard",
+            run: func(query *Query[*testOrder]) QueryResult {
+                return query.Shard(6).Delete(1)
+            },
+            want: []string{"delete orders from mydb.orders_02 orders where orders.`id` = ?"},
+        },
+        {
+            name: "insert grouped by shard",
+            run: func(query *Query[*testOrder]) QueryResult {
+                return query.Insert(&testOrder{UserId: 1}, &testOrder{UserId: 2}, &testOrder{UserId: 5})
+            },
+            want: []string{
+                "insert into mydb.orders_01 (`id`,`user_id`,`status`) values (?,?,?),(?,?,?);",
+                "insert into mydb.orders_02 (`id`,`user_id`,`status`) values (?,?,?);",
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            db, fake := newFakeDB()
+            if res := tt.run(NewQuery(table, db)); res.Err != nil {
+                t.Fatal(res.Err)
+            }
+            var got []string
+            for _, v := range fake.recorded() {
+                got = append(got, strings.TrimSpace(v.sql))
+            }
+            if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+                t.Errorf("\nwant: %s\ngot:  %s", strings.Join(tt.want, "\n      "), strings.Join(got, "\n      "))
+            }
+        })
+    }
+}
+
+func TestShardNotRouted(t *testing.T) {
+    if res := NewQuery(new(testRow)).Shard(1).result; errors.Is(res.Err, ErrTableNotSharded) == false {
+        t.Errorf("want ErrTableNotSharded, got %v", res.Err)
+    }
+    if res := NewQuery(new(testOrder)).Shard(nil).result; errors.Is(res.Err, ErrShardKeyInvalid) == false {
+        t.Errorf("want ErrShardKeyInvalid, got %v", res.Err)
+    }
+}
+
+func TestShardDeleteAlias(t *testing.T) {
+    db, fake := newFakeDB()
+    NewQuery(new(testOrder), db).Shard(6).Delete(1)
+    NewQuery(new(testRow), db).Alias("t").Delete(1)
+
+    want := []string{
+        "delete orders from mydb.orders_02 orders where orders.`id` = ?",
+        "delete mydb.test_row from mydb.test_row t where t.`id` = ?",
+    }
+    got := fake.recorded()
+    if len(got) != len(want) {
+        t.Fatalf("want %d statements, got %v", len(want), got)
+    }
+    for k, v := range want {
+        if strings.TrimSpace(got[k].sql) != v {
+            t.Errorf("want: %s\ngot:  %s", v, got[k].sql)
+        }
+    }
+}
+
+func TestShardWrites(t *testing.T) {
+    table := new(testOrder)
+
+    tests := []struct {
+        name string
+        sql  func() (string, []any, error)
+        want string
+        err  error
+    }{
+        {
+            name: "update routed by where",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).Where(&table.UserId, 5).ToUpdateSql(&table.Status, "paid")
+            },
+            want: "update mydb.orders_01 orders set orders.`status` = ? where orders.`user_id` = ?",
+        },
+        {
+            name: "update without shard key",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).WherePrimary(1).ToUpdateSql(&table.Status, "paid")
+            },
+            err: ErrShardKeyRequired,
+        },
+        {
+            name: "delete by shard",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).Shard(6).WherePrimary(1).ToDeleteSql()
+            },
+            want: "delete orders from mydb.orders_02 orders where orders.`id` = ?",
+        },
+        {
+            name: "delete without shard key",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).WherePrimary(1).ToDeleteSql()
+            },
+            err: ErrShardKeyRequired,
+        },
+        {
+            name: "upsert by shard",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).Shard(3).OnConflictUpdate(&table.Status, &table.Status).ToInsertSql(&testOrder{UserId: 3})
+            },
+            want: "insert ignore into mydb.orders_03 (`id`,`user_id`,`status`) values (?,?,?) on duplicate key update mydb.orders_03.`status` = values(`status`);",
+        },
+        {
+            name: "insert routed by rows",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).ToInsertSql(&testOrder{UserId: 1}, &testOrder{UserId: 5})
+            },
+            want: "insert into mydb.orders_01 (`id`,`user_id`,`status`) values (?,?,?),(?,?,?);",
+        },
+        {
+            name: "insert rows of different shards",
+            sql: func() (string, []any, error) {
+                return NewQuery(table).ToInsertSql(&testOrder{UserId: 1}, &testOrder{UserId: 2})
+            },
+            err: ErrShardKeyRequired,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            sqlStr, _, err := tt.sql()
+            if errors.Is(err, tt.err) == false {
+                t.Fatalf("want err %v, got %v", tt.err, err)
+            }
+            if tt.err == nil && strings.TrimSpace(sqlStr) != tt.want {
+                t.Errorf("\nwant: %s\ngot:  %s", tt.want, sqlStr)
+            }
+        })
+    }
+}
+
+func TestShardLoadData(t *testing.T) {
+    db, fake := newFakeDB()
+    res := NewQuery(new(testOrder), db).LoadData([]*testOrder{{UserId: 1}, {UserId: 2}, {UserId: 5}})
+    if res.Err != nil {
+        t.Fatal(res.Err)
+    }
+
+    statements := fake.recorded()
+    if len(statements) != 2 || strings.Contains(statements[0].sql, " into table mydb.orders_01 ") == false ||
+        strings.Contains(statements[1].sql, " into table mydb.orders_02 ") == false {
+        t.Errorf("want loaded into 2 shards, got %v", statements)
+    }
+
+    res = NewQuery(new(testOrder), db).LoadCSV(strings.NewReader("id,user_id,status\n1,1,paid\n"), nil)
+    if errors.Is(res.Err, ErrShardKeyRequired) == false {
+        t.Errorf("want ErrShardKeyRequired of csv, got %v", res.Err)
+    }
+}
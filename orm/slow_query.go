@@ -0,0 +1,79 @@
+package orm
+
+import (
+    "context"
+    "database/sql"
+    "time"
+)
+
+const slowQueryExplainTimeout = 5 * time.Second
+
+var slowQueryThreshold time.Duration
+var slowQueryExplain bool
+
+//log select slower than threshold at LogLevels.Slow, 0 to disable
+//explain: run "explain format=json" of the same sql on read db, summarized plan logged
+func SetSlowQueryThreshold(threshold time.Duration, explain bool) {
+    slowQueryThreshold = threshold
+    slowQueryExplain = explain
+}
+
+func (q *Query[T]) checkSlowQuery(ctx context.Context, info QueryInfo, res QueryResult, err error, duration time.Duration) {
+    if slowQueryThreshold <= 0 || duration < slowQueryThreshold || info.Kind != QueryKindSelect || err != nil {
+        return
+    }
+    if res.PrepareSql == "" {
+        res.PrepareSql, res.Bindings = info.Sql, info.Bindings
+    }
+
+    if slowQueryExplain == false {
+        logSlowQuery(ctx, info, res, duration, "")
+        return
+    }
+
+    db := info.db
+    if db == nil {
+        db = q.readDB()
+    }
+    //explain in background, not slowing down caller any more
+    go func() {
+        logSlowQuery(ctx, info, res, duration, explainSummary(db, res.PrepareSql, res.Bindings))
+    }()
+}
+
+func explainSummary(db *sql.DB, prepareSql string, bindings []any) string {
+    if db == nil {
+        return ""
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), slowQueryExplainTimeout)
+    defer cancel()
+
+    var data []byte
+    err := db.QueryRowContext(ctx, "explain format=json "+prepareSql, bindings...).Scan(&data)
+    if err != nil {
+        return "explain failed: " + err.Error()
+    }
+    plan, err := parseExplainJSON(data)
+    if err != nil {
+        return "explain failed: " + err.Error()
+    }
+    return summarizePlan(plan.Tables)
+}
+
+func logSlowQuery(ctx context.Context, info QueryInfo, res QueryResult, duration time.Duration, plan string) {
+    if structuredLogger != nil && logLevels.Slow < LogLevelOff {
+        args := []any{
+            "sql", res.Sql(),
+            "duration", duration,
+            "rows", res.RowsAffected,
+            "table", info.Table,
+            "db_role", string(info.Role),
+        }
+        if plan != "" {
+            args = append(args, "plan", plan)
+        }
+        logWithLevel(ctx, logLevels.Slow, "orm slow query", args...)
+    } else if infoLogger != nil {
+        infoLogger.Info("slow query", duration.String(), res.Sql(), plan)
+    }
+}
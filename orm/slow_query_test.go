@@ -0,0 +1,93 @@
+package orm
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+//slow query threshold during test
+func withSlowQueryThreshold(t *testing.T, threshold time.Duration, explain bool) {
+    SetSlowQueryThreshold(threshold, explain)
+    t.Cleanup(func() {
+        SetSlowQueryThreshold(0, false)
+    })
+}
+
+//entries with msg, waiting for background explain
+func waitLogged(l *testLogger, msg string, count int) []testLogEntry {
+    deadline := time.Now().Add(time.Second)
+    for {
+        var entries []testLogEntry
+        for _, v := range l.logged() {
+            if v.msg == msg {
+                entries = append(entries, v)
+            }
+        }
+        if len(entries) >= count || time.Now().After(deadline) {
+            return entries
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestSlowQuery(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelOff, Error: LogLevelError, Slow: LogLevelWarn})
+    withSlowQueryThreshold(t, time.Nanosecond, false)
+
+    db, _ := newFakeDB()
+    table := new(testRow)
+    NewQuery(table, db).Where(&table.Id, 1).Gets()
+    NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+
+    entries := waitLogged(l, "orm slow query", 1)
+    if len(entries) != 1 {
+        t.Fatalf("want only select logged, got %d", len(entries))
+    }
+    if entries[0].level != LogLevelWarn || entries[0].fields["table"] != "mydb.test_row" {
+        t.Errorf("want warn of mydb.test_row, got %v %v", entries[0].level, entries[0].fields)
+    }
+    if _, ok := entries[0].fields["plan"]; ok {
+        t.Errorf("want no plan without explain, got %v", entries[0].fields["plan"])
+    }
+}
+
+func TestSlowQueryExplain(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelOff, Error: LogLevelError, Slow: LogLevelWarn})
+    withSlowQueryThreshold(t, time.Nanosecond, true)
+
+    db, fake := newFakeDB()
+    fake.query = func(sqlStr string, args []any) (*fakeRows, error) {
+        if strings.HasPrefix(sqlStr, "explain format=json ") {
+            return fakeValueRows("EXPLAIN", `{"query_block": {"table": {"table_name": "test_row", "access_type": "ALL", "rows_examined_per_scan": 1000}}}`), nil
+        }
+        return &fakeRows{}, nil
+    }
+    table := new(testRow)
+    NewQuery(table, db).Where(&table.Name, "john").Gets()
+
+    entries := waitLogged(l, "orm slow query", 1)
+    if len(entries) != 1 {
+        t.Fatalf("want 1 slow query, got %d", len(entries))
+    }
+    if entries[0].fields["plan"] != "test_row(ALL, key=, rows=1000)" {
+        t.Errorf("want plan, got %v", entries[0].fields["plan"])
+    }
+
+    statements := fake.recorded()
+    if len(statements) != 2 || statements[1].sql != "explain format=json "+statements[0].sql {
+        t.Errorf("want explain of same sql, got %v", statements)
+    }
+}
+
+func TestSlowQueryUnderThreshold(t *testing.T) {
+    l := withStructuredLogger(t, LogLevels{Statement: LogLevelOff, Error: LogLevelError, Slow: LogLevelWarn})
+    withSlowQueryThreshold(t, time.Hour, true)
+
+    db, fake := newFakeDB()
+    NewQuery(new(testRow), db).Gets()
+
+    if len(l.logged()) != 0 || len(fake.recorded()) != 1 {
+        t.Errorf("want nothing logged or explained, got %v", l.logged())
+    }
+}
@@ -0,0 +1,83 @@
+package orm
+
+import (
+    "context"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+type sqlCommentKey struct{}
+
+var sqlCommentCaller bool
+
+//key values appended to statements as comment, like /* controller='user',route='%2Fusers' */
+//merged with key values already in ctx
+func WithSqlComment(ctx context.Context, tags map[string]string) context.Context {
+    merged := make(map[string]string)
+    if old, ok := ctx.Value(sqlCommentKey{}).(map[string]string); ok {
+        for k, v := range old {
+            merged[k] = v
+        }
+    }
+    for k, v := range tags {
+        merged[k] = v
+    }
+    return context.WithValue(ctx, sqlCommentKey{}, merged)
+}
+
+//append calling function outside orm package as caller='pkg.Func' to every statement
+func SetSqlCommentCaller(enable bool) {
+    sqlCommentCaller = enable
+}
+
+//append sql comment of context and caller, before trailing ';'
+func (q *Query[T]) withSqlComment(rawSql string) string {
+    var tags map[string]string
+    if q.ctx != nil {
+        tags, _ = (*q.ctx).Value(sqlCommentKey{}).(map[string]string)
+    }
+    if len(tags) == 0 && sqlCommentCaller == false {
+        return rawSql
+    }
+
+    keys := make([]string, 0, len(tags)+1)
+    for k := range tags {
+        keys = append(keys, k)
+    }
+    if sqlCommentCaller {
+        if _, ok := tags["caller"]; ok == false {
+            tags = copySqlCommentTags(tags)
+            tags["caller"] = callerFrameOutsideOrm().Function
+            keys = append(keys, "caller")
+        }
+    }
+    sort.Strings(keys)
+
+    pairs := make([]string, 0, len(keys))
+    for _, k := range keys {
+        if tags[k] == "" {
+            continue
+        }
+        pairs = append(pairs, escapeSqlComment(k)+"='"+escapeSqlComment(tags[k])+"'")
+    }
+    if len(pairs) == 0 {
+        return rawSql
+    }
+
+    trimmed := strings.TrimRight(rawSql, "; ")
+    return trimmed + " /* " + strings.Join(pairs, ",") + " */" + rawSql[len(trimmed):]
+}
+
+func copySqlCommentTags(tags map[string]string) map[string]string {
+    ret := make(map[string]string, len(tags)+1)
+    for k, v := range tags {
+        ret[k] = v
+    }
+    return ret
+}
+
+//url encoded, no quote or comment terminator left
+func escapeSqlComment(s string) string {
+    return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
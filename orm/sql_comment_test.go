@@ -0,0 +1,81 @@
+package orm
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+func TestEscapeSqlComment(t *testing.T) {
+    tests := []struct {
+        name string
+        str  string
+        want string
+    }{
+        {name: "plain", str: "checkout", want: "checkout"},
+        {name: "space", str: "order list", want: "order%20list"},
+        {name: "quote", str: "o'brien", want: "o%27brien"},
+        {name: "comment terminator", str: "a*/drop table user;/*", want: "a%2A%2Fdrop%20table%20user%3B%2F%2A"},
+        {name: "plus", str: "a+b", want: "a%2Bb"},
+        {name: "path", str: "/api/orders?id=1", want: "%2Fapi%2Forders%3Fid%3D1"},
+        {name: "unicode", str: "订单", want: "%E8%AE%A2%E5%8D%95"},
+        {name: "empty", str: "", want: ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := escapeSqlComment(tt.str); got != tt.want {
+                t.Errorf("want %q, got %q", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestWithSqlComment(t *testing.T) {
+    ctx := WithSqlComment(context.Background(), map[string]string{"route": "/orders", "controller": "order"})
+    ctx = WithSqlComment(ctx, map[string]string{"action": "list", "controller": "order list", "empty": ""})
+
+    db, fake := newFakeDB()
+    table := new(testRow)
+
+    NewQuery(table, db).WithContext(ctx).Gets(1)
+    NewQuery(table, db).WithContext(ctx).Insert(&testRow{Name: "john"})
+    NewQuery(table, db).Gets(1)
+
+    comment := " /* action='list',controller='order%20list',route='%2Forders' */"
+    statements := fake.recorded()
+    if len(statements) != 3 {
+        t.Fatalf("want 3 statements, got %d", len(statements))
+    }
+    if strings.HasSuffix(statements[0].sql, "`id` = ?"+comment) == false {
+        t.Errorf("want comment appended, got %s", statements[0].sql)
+    }
+    if strings.HasSuffix(statements[1].sql, "(?,?,?)"+comment+";") == false {
+        t.Errorf("want comment before ';', got %s", statements[1].sql)
+    }
+    if strings.Contains(statements[2].sql, "/*") {
+        t.Errorf("want no comment without context, got %s", statements[2].sql)
+    }
+}
+
+func TestSqlCommentCaller(t *testing.T) {
+    SetSqlCommentCaller(true)
+    defer SetSqlCommentCaller(false)
+
+    db, fake := newFakeDB()
+    NewQuery(new(testRow), db).Gets(1)
+    ctx := WithSqlComment(context.Background(), map[string]string{"caller": "checkout"})
+    NewQuery(new(testRow), db).WithContext(ctx).Gets(1)
+
+    statements := fake.recorded()
+    if len(statements) != 2 {
+        t.Fatalf("want 2 statements, got %d", len(statements))
+    }
+    //tests are in orm package, caller is the test runner
+    if strings.Contains(statements[0].sql, " /* caller='testing.tRunner' */") == false {
+        t.Errorf("want caller comment, got %s", statements[0].sql)
+    }
+    if strings.HasSuffix(statements[1].sql, " /* caller='checkout' */") == false {
+        t.Errorf("want caller of context kept, got %s", statements[1].sql)
+    }
+}
@@ -0,0 +1,159 @@
+package orm
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+var strictColumns bool
+
+//column, table.column, db.table.column, with optional backticks
+var columnNamePattern = regexp.MustCompile("^`?[A-Za-z_][A-Za-z0-9_$]*`?(\\.`?[A-Za-z_][A-Za-z0-9_$]*`?){0,2}$")
+
+//alias of select column, like "count(*) as total"
+var columnAliasPattern = regexp.MustCompile("(?i)\\s+as\\s+`?([A-Za-z_][A-Za-z0-9_$]*)`?$")
+
+//check string column names against fields of tables in query, for all queries
+//checked when sql generated, after all tables joined, expressions like "count(*)" and Raw are not checked
+func SetStrictColumns(strict bool) {
+    strictColumns = strict
+}
+
+//check string column names of this query, overrides SetStrictColumns
+func (q *Query[T]) StrictColumns(strict bool) *Query[T] {
+    q.strictColumns = &strict
+    return q
+}
+
+func (q *Query[T]) strictColumnsEnabled() bool {
+    if q.strictColumns != nil {
+        return *q.strictColumns
+    }
+    return strictColumns
+}
+
+//ErrColumnNotExisted with near match if column not in tables of query
+func (q *Query[T]) checkColumnName(column string) error {
+    if q.strictColumnsEnabled() == false || columnNamePattern.MatchString(column) == false {
+        return nil
+    }
+
+    parts := strings.Split(column, ".")
+    for k := range parts {
+        parts[k] = strings.Trim(parts[k], "`")
+    }
+    name := parts[len(parts)-1]
+    prefix := strings.Join(parts[:len(parts)-1], ".")
+
+    var candidates []string
+    for _, t := range q.tables {
+        if prefix != "" && prefix != t.getAlias() && prefix != t.getTableName() && prefix != t.table.TableName() {
+            continue
+        }
+        if t.ormFields == nil {
+            //subquery table, columns unknown
+            return nil
+        }
+        for _, v := range t.ormFields {
+            if v == name {
+                return nil
+            }
+            candidates = append(candidates, v)
+        }
+    }
+    if prefix == "" {
+        for _, v := range q.selectAliases() {
+            if v == name {
+                return nil
+            }
+            candidates = append(candidates, v)
+        }
+    } else if len(candidates) == 0 {
+        //table of prefix not in query, like outer table of subquery
+        return nil
+    }
+
+    if suggestion := nearestName(name, candidates); suggestion != "" {
+        return fmt.Errorf("%w: %s, did you mean %s", ErrColumnNotExisted, column, suggestion)
+    }
+    return fmt.Errorf("%w: %s", ErrColumnNotExisted, column)
+}
+
+//error of pending columns still not in tables, when sql generated
+func (q *Query[T]) checkPendingColumns() error {
+    for _, v := range q.pendingColumns {
+        if err := q.checkColumnName(v); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//aliases of select columns
+func (q *Query[T]) selectAliases() []string {
+    var ret []string
+    for _, v := range q.columns {
+        if str, ok := v.(string); ok {
+            if m := columnAliasPattern.FindStringSubmatch(str); m != nil {
+                ret = append(ret, m[1])
+            }
+        }
+    }
+    return ret
+}
+
+//candidate of least edit distance, or starting with name, empty if not near enough
+func nearestName(name string, candidates []string) string {
+    best, bestDistance := "", -1
+    for _, v := range candidates {
+        d := editDistance(strings.ToLower(name), strings.ToLower(v))
+        if bestDistance < 0 || d < bestDistance || (d == bestDistance && v < best) {
+            best, bestDistance = v, d
+        }
+    }
+    maxDistance := len(name) / 3
+    if maxDistance < 2 {
+        maxDistance = 2
+    }
+    if bestDistance >= 0 && bestDistance <= maxDistance {
+        return best
+    }
+
+    //like created for created_at
+    best = ""
+    for _, v := range candidates {
+        if strings.HasPrefix(strings.ToLower(v), strings.ToLower(name)) && (best == "" || v < best) {
+            best = v
+        }
+    }
+    return best
+}
+
+//levenshtein distance
+func editDistance(a, b string) int {
+    prev := make([]int, len(b)+1)
+    cur := make([]int, len(b)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+    for i := 1; i <= len(a); i++ {
+        cur[0] = i
+        for j := 1; j <= len(b); j++ {
+            cost := 1
+            if a[i-1] == b[j-1] {
+                cost = 0
+            }
+            cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+        }
+        prev, cur = cur, prev
+    }
+    return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+    if a < b {
+        return a
+    }
+    return b
+}
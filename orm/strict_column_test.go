@@ -0,0 +1,145 @@
+package orm
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestEditDistance(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {a: "", b: "", want: 0},
+        {a: "", b: "name", want: 4},
+        {a: "name", b: "", want: 4},
+        {a: "name", b: "name", want: 0},
+        {a: "nmae", b: "name", want: 2},
+        {a: "user_id", b: "userid", want: 1},
+        {a: "kitten", b: "sitting", want: 3},
+        {a: "email", b: "phone", want: 5},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+            if got := editDistance(tt.a, tt.b); got != tt.want {
+                t.Errorf("want %d, got %d", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestNearestName(t *testing.T) {
+    columns := []string{"id", "user_id", "name", "email", "created_at", "updated_at"}
+
+    tests := []struct {
+        name       string
+        candidates []string
+        want       string
+    }{
+        {name: "nmae", candidates: columns, want: "name"},
+        {name: "userid", candidates: columns, want: "user_id"},
+        {name: "EMAIL", candidates: columns, want: "email"},
+        {name: "created", candidates: columns, want: "created_at"},
+        {name: "update", candidates: columns, want: "updated_at"},
+        {name: "ab", candidates: []string{"cd", "ab1", "xy"}, want: "ab1"},
+        {name: "ag", candidates: []string{"ab", "ac"}, want: "ab"},
+        {name: "address", candidates: columns, want: ""},
+        {name: "name", candidates: nil, want: ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := nearestName(tt.name, tt.candidates); got != tt.want {
+                t.Errorf("want %q, got %q", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestStrictColumns(t *testing.T) {
+    table := new(testRow)
+
+    tests := []struct {
+        name  string
+        query func() *Query[*testRow]
+        err   string
+    }{
+        {name: "typo", query: func() *Query[*testRow] {
+            return NewQuery(table).Where("nmae", "john")
+        }, err: "column not existed: nmae, did you mean name"},
+        {name: "no near name", query: func() *Query[*testRow] {
+            return NewQuery(table).OrderBy("address")
+        }, err: "column not existed: address"},
+        {name: "prefixed", query: func() *Query[*testRow] {
+            return NewQuery(table).Where("test_row.dat", "x")
+        }, err: "column not existed: test_row.dat, did you mean data"},
+        {name: "field", query: func() *Query[*testRow] {
+            return NewQuery(table).Where("name", "john").Where("mydb.test_row.`id`", 1)
+        }},
+        {name: "select alias", query: func() *Query[*testRow] {
+            return NewQuery(table).Select("count(*) as total", "name").GroupBy("name").OrderBy("total")
+        }},
+        {name: "expression and raw", query: func() *Query[*testRow] {
+            return NewQuery(table).Where("ifnull(nmae, '')", "").Where(Raw("nmae"), "")
+        }},
+        {name: "other table prefix", query: func() *Query[*testRow] {
+            return NewQuery(table).Where("outer_table.nmae", "")
+        }},
+        {name: "disabled by query", query: func() *Query[*testRow] {
+            return NewQuery(table).StrictColumns(false).Where("nmae", "john")
+        }},
+    }
+
+    SetStrictColumns(true)
+    defer SetStrictColumns(false)
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, _, err := tt.query().ToSelectSql()
+            if tt.err == "" && err != nil {
+                t.Errorf("want no error, got %v", err)
+            } else if tt.err != "" && (errors.Is(err, ErrColumnNotExisted) == false || err.Error() != tt.err) {
+                t.Errorf("want %s, got %v", tt.err, err)
+            }
+        })
+    }
+}
+
+func TestStrictColumnsDisabled(t *testing.T) {
+    if _, _, err := NewQuery(new(testRow)).Where("nmae", "john").ToSelectSql(); err != nil {
+        t.Errorf("want not checked by default, got %v", err)
+    }
+    if _, _, err := NewQuery(new(testRow)).StrictColumns(true).Where("nmae", "john").ToSelectSql(); errors.Is(err, ErrColumnNotExisted) == false {
+        t.Errorf("want checked by query, got %v", err)
+    }
+}
+
+func TestStrictColumnsJoinedLater(t *testing.T) {
+    table, event := new(testRow), new(testEvent)
+    query := func(column string) *Query[*testRow] {
+        return NewQuery(table).StrictColumns(true).Where(column, "2024-01-01").Join(event, func(join *Query[*testRow]) *Query[*testRow] {
+            return join.Where(&table.Id, &event.Id)
+        })
+    }
+
+    if _, _, err := query("created_at").ToSelectSql(); err != nil {
+        t.Errorf("select: want column of table joined after where, got %v", err)
+    }
+    if _, _, err := query("created_at").ToUpdateSql(&table.Name, "x"); err != nil {
+        t.Errorf("update: want column of table joined after where, got %v", err)
+    }
+    if _, _, err := query("created_at").ToDeleteSql(); err != nil {
+        t.Errorf("delete: want column of table joined after where, got %v", err)
+    }
+
+    want := "column not existed: creatd_at, did you mean created_at"
+    if _, _, err := query("creatd_at").ToSelectSql(); err == nil || err.Error() != want {
+        t.Errorf("select: want %s, got %v", want, err)
+    }
+    if _, _, err := query("creatd_at").ToUpdateSql(&table.Name, "x"); err == nil || err.Error() != want {
+        t.Errorf("update: want %s, got %v", want, err)
+    }
+    if _, _, err := query("creatd_at").ToDeleteSql(); err == nil || err.Error() != want {
+        t.Errorf("delete: want %s, got %v", want, err)
+    }
+}
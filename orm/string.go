@@ -93,6 +93,12 @@ func varToString(i any) string {
     }
 }
 
+//column name without table prefix and quotes
+func columnName(column string) string {
+    dotIndex := strings.LastIndex(column, ".")
+    return strings.Trim(column[dotIndex+1:], "`")
+}
+
 func stringIsPrintable(s string) bool {
     for _, r := range s {
         if !unicode.IsPrint(r) {
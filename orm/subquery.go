@@ -18,6 +18,7 @@ type SubQuery struct {
     tx        *sql.Tx
     err       error
     unionAll  bool
+    tables    []string //tables read, for query cache invalidation
 }
 
 func (m SubQuery) Connections() []*sql.DB {
@@ -0,0 +1,147 @@
+package orm
+
+import (
+    "context"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+//key value of span, like db.system=mysql
+type Attribute struct {
+    Key   string
+    Value any
+}
+
+//span of a statement or transaction, like trace.Span of opentelemetry
+type Span interface {
+    SetAttributes(attrs ...Attribute)
+    RecordError(err error)
+    End()
+}
+
+//start span parented from span in ctx, like trace.Tracer of opentelemetry
+//adapter of opentelemetry: tracer.Start(ctx, name), attribute.KeyValue from Attribute
+type Tracer interface {
+    Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+var tracer Tracer
+
+//span for each Execute, GetTo, Transaction
+func SetTracer(t Tracer) {
+    tracer = t
+}
+
+func startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+    if tracer == nil {
+        return ctx, nil
+    }
+    ctx, span := tracer.Start(ctx, name)
+    span.SetAttributes(attrs...)
+    return ctx, span
+}
+
+func endSpan(span Span, err error, attrs ...Attribute) {
+    if span == nil {
+        return
+    }
+    span.SetAttributes(attrs...)
+    if err != nil {
+        span.RecordError(err)
+    }
+    span.End()
+}
+
+func statementSpanAttributes(info QueryInfo) []Attribute {
+    return []Attribute{
+        {Key: "db.system", Value: "mysql"},
+        {Key: "db.operation", Value: string(info.Kind)},
+        {Key: "db.sql.table", Value: info.Table},
+        {Key: "db.statement", Value: Fingerprint(info.Sql)},
+    }
+}
+
+//tracer keeping ended spans in memory, for tests
+type InMemoryTracer struct {
+    mu     sync.Mutex
+    spans  []RecordedSpan
+    nextId int64
+}
+
+type RecordedSpan struct {
+    Id         int64
+    ParentId   int64 //0 for root span
+    Name       string
+    Attributes map[string]any
+    Err        error
+    Start      time.Time
+    End        time.Time
+}
+
+type inMemorySpanKey struct{}
+
+type inMemorySpan struct {
+    tracer *InMemoryTracer
+    span   RecordedSpan
+    mu     sync.Mutex
+}
+
+func NewInMemoryTracer() *InMemoryTracer {
+    return &InMemoryTracer{}
+}
+
+func (t *InMemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+    span := &inMemorySpan{tracer: t, span: RecordedSpan{
+        Id:         atomic.AddInt64(&t.nextId, 1),
+        Name:       name,
+        Attributes: make(map[string]any),
+        Start:      time.Now(),
+    }}
+    if parent, ok := ctx.Value(inMemorySpanKey{}).(*inMemorySpan); ok {
+        span.span.ParentId = parent.span.Id
+    }
+    return context.WithValue(ctx, inMemorySpanKey{}, span), span
+}
+
+//ended spans in end order
+func (t *InMemoryTracer) Spans() []RecordedSpan {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return append([]RecordedSpan{}, t.spans...)
+}
+
+func (t *InMemoryTracer) Reset() {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.spans = nil
+}
+
+func (s *inMemorySpan) SetAttributes(attrs ...Attribute) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, v := range attrs {
+        s.span.Attributes[v.Key] = v.Value
+    }
+}
+
+func (s *inMemorySpan) RecordError(err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.span.Err = err
+}
+
+func (s *inMemorySpan) End() {
+    s.mu.Lock()
+    s.span.End = time.Now()
+    span := s.span
+    span.Attributes = make(map[string]any, len(s.span.Attributes))
+    for k, v := range s.span.Attributes {
+        span.Attributes[k] = v
+    }
+    s.mu.Unlock()
+
+    s.tracer.mu.Lock()
+    defer s.tracer.mu.Unlock()
+    s.tracer.spans = append(s.tracer.spans, span)
+}
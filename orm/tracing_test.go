@@ -0,0 +1,126 @@
+package orm
+
+import (
+    "context"
+    "database/sql/driver"
+    "errors"
+    "strings"
+    "testing"
+)
+
+//tracer during test
+func withTracer(t *testing.T) *InMemoryTracer {
+    tr := NewInMemoryTracer()
+    old := tracer
+    SetTracer(tr)
+    t.Cleanup(func() {
+        tracer = old
+    })
+    return tr
+}
+
+func TestStatementSpan(t *testing.T) {
+    tr := withTracer(t)
+
+    db, fake := newFakeDB()
+    fake.exec = func(sqlStr string, args []any) (driver.Result, error) {
+        if strings.HasPrefix(sqlStr, "delete") {
+            return nil, errors.New("deadlock")
+        }
+        return fakeResult{rowsAffected: 3}, nil
+    }
+    table := new(testRow)
+
+    NewQuery(table, db).Where(&table.Id, 1).Update(&table.Name, "john")
+    NewQuery(table, db).Delete(1)
+
+    spans := tr.Spans()
+    if len(spans) != 2 {
+        t.Fatalf("want 2 spans, got %d", len(spans))
+    }
+
+    update := spans[0]
+    if update.Name != "update mydb.test_row" || update.ParentId != 0 || update.Err != nil {
+        t.Errorf("want root update span, got %+v", update)
+    }
+    want := map[string]any{
+        "db.system":        "mysql",
+        "db.operation":     "update",
+        "db.sql.table":     "mydb.test_row",
+        "db.statement":     "update mydb.test_row set mydb.test_row.`name` = ? where mydb.test_row.`id` = ?",
+        "db.rows_affected": int64(3),
+    }
+    for k, v := range want {
+        if update.Attributes[k] != v {
+            t.Errorf("want %s %v, got %v", k, v, update.Attributes[k])
+        }
+    }
+    if update.End.Before(update.Start) {
+        t.Errorf("want end after start, got %v %v", update.Start, update.End)
+    }
+
+    if spans[1].Name != "delete mydb.test_row" || spans[1].Err == nil || spans[1].Err.Error() != "deadlock" {
+        t.Errorf("want delete span with error, got %+v", spans[1])
+    }
+}
+
+func TestTransactionSpan(t *testing.T) {
+    tr := withTracer(t)
+
+    db, _ := newFakeDB()
+    table := new(testRow)
+
+    err := NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        query.Where(&table.Id, 1).Update(&table.Name, "john")
+        _, res := query.Gets(1)
+        return res.Err
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    spans := tr.Spans()
+    if len(spans) != 3 {
+        t.Fatalf("want 3 spans, got %d", len(spans))
+    }
+    tx := spans[2]
+    if tx.Name != "transaction" || tx.Attributes["db.transaction"] != "commit" || tx.ParentId != 0 {
+        t.Errorf("want committed transaction span ended last, got %+v", tx)
+    }
+    for _, v := range spans[:2] {
+        if v.ParentId != tx.Id {
+            t.Errorf("want %s parented by transaction, got parent %d", v.Name, v.ParentId)
+        }
+    }
+
+    tr.Reset()
+    err = NewQuery(table, db).Transaction(func(query *Query[*testRow]) error {
+        return errors.New("canceled")
+    })
+    spans = tr.Spans()
+    if len(spans) != 1 || spans[0].Attributes["db.transaction"] != "rollback" || spans[0].Err != err {
+        t.Errorf("want rolled back transaction span, got %+v", spans)
+    }
+}
+
+func TestSpanParentFromContext(t *testing.T) {
+    tr := withTracer(t)
+
+    ctx, parent := tr.Start(context.Background(), "request")
+    db, _ := newFakeDB()
+    NewQuery(new(testRow), db).WithContext(ctx).Gets()
+    parent.End()
+
+    spans := tr.Spans()
+    if len(spans) != 2 || spans[0].Name != "select mydb.test_row" || spans[0].ParentId != spans[1].Id {
+        t.Errorf("want select parented by request span, got %+v", spans)
+    }
+}
+
+func TestNoTracer(t *testing.T) {
+    ctx, span := startSpan(context.Background(), "select")
+    if span != nil || ctx != context.Background() {
+        t.Errorf("want no span without tracer, got %v", span)
+    }
+    endSpan(nil, errors.New("ignored"))
+}
@@ -1,6 +1,7 @@
 package orm
 
 type updateColumn struct {
-    col any
-    val any
+    col      any
+    val      any
+    bindings []any //of val as Raw
 }